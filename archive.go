@@ -0,0 +1,257 @@
+package snake
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ArchiveOptions 目录归档选项
+type ArchiveOptions struct {
+	TarOptions                    // tar 压缩选项，未指定压缩方式时根据目标扩展名推断，Ignore 对 zip 同样有效
+	Include    []string           // 只归档匹配的路径，为空时归档全部
+	Exclude    []string           // 跳过匹配的路径，匹配的目录整体跳过
+	OnSkip     func(SkippedEntry) // 路径被 Ignore、.snakeignore 或 Exclude 跳过时调用
+}
+
+// ---------------------------------------
+// 处理 :
+
+// Archive 将目录归档为target，格式由扩展名决定：.zip、.tar、.tar.gz、.tar.bz2、.tar.xz、.tar.zst
+// 条目使用相对当前目录的路径，包含空目录与符号链接，保留修改时间与权限，
+// 文件内容边读边写，不在内存中缓存。
+// 目录下存在 .snakeignore 时，其中的规则追加在 Ignore 之后。
+// 例子：
+// snake.FS("./dist").Archive("dist.tar.gz", snake.ArchiveOptions{Exclude: []string{"*.map"}})
+func (sk *snakeFileSystem) Archive(target string, opts ...ArchiveOptions) error {
+	root, ok := sk.pathdst()
+	if !ok {
+		return sk.err
+	}
+
+	if target, ok = sk.pathdst(target); !ok {
+		return sk.err
+	}
+
+	if !sk.IsDir() {
+		return fmt.Errorf("snake: %s is not a directory", root)
+	}
+
+	// 目录中的符号链接不能指向 Jail 根目录外
+	if err := sk.resolveTree(root); err != nil {
+		sk.err = err
+		return err
+	}
+
+	var opt ArchiveOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	isZip := strings.EqualFold(filepath.Ext(target), ".zip")
+	if !isZip && opt.Codec == "" {
+		if opt.Codec = codecFromExt(target); opt.Codec == "" {
+			return fmt.Errorf("snake: unknown archive extension %s", filepath.Base(target))
+		}
+	}
+
+	// 目录中的 .snakeignore 规则追加在 Ignore 之后
+	ignore := &IgnoreRules{}
+	if opt.Ignore != nil {
+		ignore.rules = append(ignore.rules, opt.Ignore.rules...)
+	}
+	if file := filepath.Join(root, IgnoreFile); FS(file).IsFile() {
+		if err := ignore.Load(file); err != nil {
+			return err
+		}
+	}
+	opt.Ignore = nil
+
+	skip := func(rel, rule string, info os.FileInfo) error {
+		if opt.OnSkip != nil {
+			opt.OnSkip(SkippedEntry{Path: rel, Rule: rule})
+		}
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+
+	var add func(rel string, info os.FileInfo, path string) error
+	var closer func(err error) error // err不为nil时放弃归档
+	var pending *pendingFile
+
+	if isZip {
+		z := Zip(target, ZipOptions{Reproducible: opt.Reproducible, ModTime: opt.ModTime, Manifest: opt.Manifest})
+		add = func(rel string, info os.FileInfo, path string) error {
+			return archiveZipEntry(z, rel, info, path)
+		}
+		pending = z.file
+		closer = func(err error) error {
+			if err != nil {
+				z.err = err
+			}
+			return z.Close()
+		}
+	} else {
+		t := Tar(target, opt.TarOptions)
+		add = func(rel string, info os.FileInfo, path string) error {
+			return archiveTarEntry(t, rel, info, path)
+		}
+		pending = t.file
+		closer = func(err error) error {
+			if err != nil {
+				t.err = err
+			}
+			return t.Close()
+		}
+	}
+
+	// 目标及写入中的临时文件位于被归档目录内时不能归档自身
+	abs, _ := filepath.Abs(target)
+	var tmp string
+	if pending != nil {
+		tmp, _ = filepath.Abs(pending.Name())
+	}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if p, _ := filepath.Abs(path); p == abs || p == tmp {
+			return nil
+		}
+
+		if rule, ok := ignore.Match(rel, info.IsDir()); ok {
+			return skip(rel, rule, info)
+		}
+
+		if matchPath(opt.Exclude, rel) {
+			return skip(rel, "exclude", info)
+		}
+
+		// 目录中已有的同名清单被归档时生成的清单取代
+		if opt.Manifest != "" && rel == ManifestName(opt.Manifest) {
+			return skip(rel, "manifest", info)
+		}
+
+		if len(opt.Include) > 0 && !matchPath(opt.Include, rel) {
+			return nil
+		}
+
+		return add(rel, info, path)
+	})
+
+	if e := closer(err); err == nil {
+		err = e
+	}
+
+	return err
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// archiveTarEntry 添加目录、文件或符号链接到 tar 归档
+func archiveTarEntry(t *Tarlib, rel string, info os.FileInfo, path string) error {
+	switch {
+	case info.IsDir():
+		_, err := t.AddReader(rel+"/", info, nil)
+		return err
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		_, err = t.AddLink(rel, info, link)
+		return err
+	case info.Mode().IsRegular():
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = t.AddReader(rel, info, f)
+		return err
+	}
+	return nil
+}
+
+// archiveZipEntry 添加目录、文件或符号链接到 zip 归档，保留修改时间与权限
+func archiveZipEntry(z *Ziplib, rel string, info os.FileInfo, path string) error {
+	opt := ZipEntryOptions{Modified: info.ModTime(), Mode: info.Mode()}
+
+	switch {
+	case info.IsDir():
+		_, err := z.AddReader(rel+"/", strings.NewReader(""), opt)
+		return err
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		_, err = z.AddReader(rel, strings.NewReader(link), opt)
+		return err
+	case info.Mode().IsRegular():
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = z.AddReader(rel, f, opt)
+		return err
+	}
+	return nil
+}
+
+// pendingFile 写入中的归档，内容先写入目标同目录下的临时文件
+type pendingFile struct {
+	*os.File
+	target string
+}
+
+// createPending 在target同目录下创建临时文件，目录不存在时创建
+func createPending(target string) (*pendingFile, error) {
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(target)+".*")
+	if err != nil {
+		return nil, err
+	}
+	return &pendingFile{File: f, target: target}, nil
+}
+
+// commit 关闭临时文件，err为nil时重命名为目标文件，否则删除临时文件
+// 已存在的目标文件保留原权限，新建的为 0644。
+func (f *pendingFile) commit(err error) error {
+	if e := f.Close(); err == nil {
+		err = e
+	}
+
+	if err == nil {
+		mode := os.FileMode(0644)
+		if info, e := os.Stat(f.target); e == nil {
+			mode = info.Mode().Perm()
+		}
+		err = os.Chmod(f.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), f.target)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
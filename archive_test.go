@@ -0,0 +1,269 @@
+package snake
+
+import (
+	"archive/tar"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// archiveTree 生成归档测试使用的目录
+func archiveTree(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "src")
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	os.MkdirAll(filepath.Join(dir, "empty"), 0755)
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("alpha"), 0644)
+	os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("bravo"), 0644)
+	os.WriteFile(filepath.Join(dir, "sub", "c.map"), []byte("map"), 0644)
+	os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh\n"), 0755)
+	symlink(t, "a.txt", filepath.Join(dir, "link"))
+	return dir
+}
+
+// archiveNames 返回归档中的所有路径，目录以 / 结尾
+func archiveNames(t *testing.T, path string) []string {
+	t.Helper()
+	ar, err := OpenArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ar.Close()
+
+	var res []string
+	err = fs.WalkDir(ar, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || name == "." {
+			return err
+		}
+		if d.IsDir() {
+			name += "/"
+		}
+		res = append(res, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestArchiveFormats(t *testing.T) {
+	want := []string{"a.txt", "empty/", "link", "run.sh", "sub/", "sub/b.txt", "sub/c.map"}
+
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tar.bz2", ".tar.xz", ".tar.zst"} {
+		t.Run(ext, func(t *testing.T) {
+			dir := archiveTree(t)
+			target := filepath.Join(filepath.Dir(dir), "out"+ext)
+			if err := FS(dir).Archive(target); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := archiveNames(t, target); !reflect.DeepEqual(got, want) {
+				t.Fatalf("entries = %v, want %v", got, want)
+			}
+
+			ar, err := OpenArchive(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ar.Close()
+			if b, _ := ar.ReadFile("sub/b.txt"); string(b) != "bravo" {
+				t.Fatalf("sub/b.txt = %q", b)
+			}
+			if info, err := ar.Stat("run.sh"); err != nil || info.Mode().Perm() != 0755 {
+				t.Fatalf("run.sh mode = %v, %v", info, err)
+			}
+			node, err := ar.lookup("link", false)
+			if err != nil || node.mode&fs.ModeSymlink == 0 {
+				t.Fatalf("link is not a symlink: %v", err)
+			}
+			if link, err := ar.readlink(node); err != nil || link != "a.txt" {
+				t.Fatalf("link = %q, %v", link, err)
+			}
+		})
+	}
+}
+
+func TestArchiveOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		ignore  string // .snakeignore 的内容
+		opt     ArchiveOptions
+		want    []string
+		skipped []string
+	}{
+		{
+			name:    "exclude",
+			opt:     ArchiveOptions{Exclude: []string{"*.map", "empty"}},
+			want:    []string{"a.txt", "link", "run.sh", "sub/", "sub/b.txt"},
+			skipped: []string{"empty", "sub/c.map"},
+		},
+		{
+			name: "include",
+			opt:  ArchiveOptions{Include: []string{"sub/*.txt"}},
+			want: []string{"sub/", "sub/b.txt"},
+		},
+		{
+			name:    "ignore",
+			opt:     ArchiveOptions{TarOptions: TarOptions{Ignore: Ignore("*.sh")}},
+			want:    []string{"a.txt", "empty/", "link", "sub/", "sub/b.txt", "sub/c.map"},
+			skipped: []string{"run.sh"},
+		},
+		{
+			name:    "snakeignore",
+			ignore:  "sub/\n",
+			want:    []string{".snakeignore", "a.txt", "empty/", "link", "run.sh"},
+			skipped: []string{"sub"},
+		},
+	}
+
+	for _, ext := range []string{".zip", ".tar.gz"} {
+		for _, tt := range tests {
+			t.Run(ext+"/"+tt.name, func(t *testing.T) {
+				dir := archiveTree(t)
+				if tt.ignore != "" {
+					os.WriteFile(filepath.Join(dir, IgnoreFile), []byte(tt.ignore), 0644)
+				}
+
+				var skipped []string
+				opt := tt.opt
+				opt.OnSkip = func(e SkippedEntry) { skipped = append(skipped, e.Path) }
+
+				target := filepath.Join(filepath.Dir(dir), "out"+ext)
+				if err := FS(dir).Archive(target, opt); err != nil {
+					t.Fatal(err)
+				}
+				if got := archiveNames(t, target); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("entries = %v, want %v", got, tt.want)
+				}
+				sort.Strings(skipped)
+				if !reflect.DeepEqual(skipped, tt.skipped) {
+					t.Fatalf("skipped = %v, want %v", skipped, tt.skipped)
+				}
+			})
+		}
+	}
+}
+
+func TestArchiveManifest(t *testing.T) {
+	for _, ext := range []string{".zip", ".tar.gz"} {
+		t.Run(ext, func(t *testing.T) {
+			dir := archiveTree(t)
+			// 目录中已有的同名清单被生成的清单取代
+			os.WriteFile(filepath.Join(dir, "SHA256SUMS"), []byte("stale"), 0644)
+
+			target := filepath.Join(filepath.Dir(dir), "out"+ext)
+			opt := ArchiveOptions{TarOptions: TarOptions{Manifest: HashSHA256}}
+			if err := FS(dir).Archive(target, opt); err != nil {
+				t.Fatal(err)
+			}
+
+			ar, err := OpenArchive(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ar.Close()
+			if report, err := ar.FS().VerifyManifest("SHA256SUMS"); err != nil {
+				t.Fatalf("VerifyManifest: %v, %+v", err, report)
+			}
+		})
+	}
+}
+
+// TestArchiveTargetInside 目标位于被归档目录内时，目标及写入中的临时文件不被归档
+func TestArchiveTargetInside(t *testing.T) {
+	for _, ext := range []string{".zip", ".tar.gz"} {
+		t.Run(ext, func(t *testing.T) {
+			dir := archiveTree(t)
+			target := filepath.Join(dir, "out"+ext)
+			if err := FS(dir).Archive(target); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range archiveNames(t, target) {
+				if strings.Contains(name, "out"+ext) {
+					t.Fatalf("archive contains %s", name)
+				}
+			}
+		})
+	}
+}
+
+// TestArchiveFailure 归档失败或未完成时不留下不完整的文件，已存在的目标保持不变
+func TestArchiveFailure(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(target string) error
+	}{
+		{"archive", func(target string) error {
+			opt := ArchiveOptions{TarOptions: TarOptions{Manifest: "crc"}}
+			return FS(archiveTree(t)).Archive(target, opt)
+		}},
+		{"archive zip", func(target string) error {
+			opt := ArchiveOptions{TarOptions: TarOptions{Manifest: "crc"}}
+			return FS(archiveTree(t)).Archive(target+".zip", opt)
+		}},
+		{"tar close", func(target string) error {
+			return Tar(target, TarOptions{Manifest: "crc"}).Close()
+		}},
+		{"zip close", func(target string) error {
+			return Zip(target, ZipOptions{Manifest: "crc"}).Close()
+		}},
+		{"tar abandoned", func(target string) error {
+			header := &tar.Header{Name: "a.txt", Mode: 0644, Size: 1, Typeflag: tar.TypeReg}
+			Tar(target).Add("a.txt", header.FileInfo(), []byte("a"))
+			return os.ErrClosed
+		}},
+		{"zip abandoned", func(target string) error {
+			Zip(target).Add("a.txt", []byte("a"))
+			return os.ErrClosed
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			target := filepath.Join(dir, "out.tar.gz")
+			existing := filepath.Join(dir, "existing.tar.gz")
+			os.WriteFile(existing, []byte("old"), 0600)
+
+			if err := tt.write(target); err == nil {
+				t.Fatal("expected an error")
+			}
+			if FS(target).Exist() || FS(target+".zip").Exist() {
+				t.Fatal("partial archive left behind")
+			}
+
+			if err := tt.write(existing); err == nil {
+				t.Fatal("expected an error")
+			}
+			if b, _ := os.ReadFile(existing); string(b) != "old" {
+				t.Fatalf("existing target overwritten with %q", b)
+			}
+		})
+	}
+}
+
+func TestArchiveReplace(t *testing.T) {
+	dir := archiveTree(t)
+	target := filepath.Join(filepath.Dir(dir), "out.tar")
+	os.WriteFile(target, []byte("old"), 0600)
+
+	if err := FS(dir).Archive(target); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(target)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("target mode = %v, %v, want 0600", info, err)
+	}
+	if len(archiveNames(t, target)) == 0 {
+		t.Fatal("empty archive")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(target)); len(entries) != 2 {
+		t.Fatalf("temporary files left: %v", entries)
+	}
+}
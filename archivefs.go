@@ -0,0 +1,1038 @@
+package snake
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/configor"
+)
+
+// ErrReadOnly 归档视图为只读，不能修改
+var ErrReadOnly = errors.New("snake: read-only archive file system")
+
+// errNestedArchive 归档视图中的条目不能作为归档读取
+var errNestedArchive = fmt.Errorf("snake: nested archive: %w", errors.ErrUnsupported)
+
+// ArchiveReader 以只读方式打开的 zip 或 tar 归档，实现 io/fs.FS
+// 打开时只读取条目目录，条目内容在读取时才解压，不写入磁盘。
+type ArchiveReader struct {
+	Path     string // 归档文件路径
+	format   archiveFormat
+	file     *os.File
+	zip      *zip.Reader
+	password string
+	root     *archiveNode
+}
+
+// ArchiveReaderOptions 打开归档的选项
+type ArchiveReaderOptions struct {
+	Password string // 加密 zip 条目的密码
+	Charset  string // 未标记 UTF-8 的 zip 条目名称的编码，如 GBK，为空时自动检测
+}
+
+// archiveNode 归档中的文件、目录或符号链接，缺失的上级目录自动补全
+type archiveNode struct {
+	path     string // 清理后的路径，根目录为 "."
+	mode     fs.FileMode
+	size     int64
+	modTime  time.Time
+	link     string    // 符号链接目标，zip 中读取时才加载
+	hard     string    // tar 硬链接目标
+	zip      *zip.File // zip 条目
+	index    int       // tar 条目序号
+	offset   int64     // 未压缩 tar 中内容的偏移，-1 时需顺序读取
+	children map[string]*archiveNode
+}
+
+// ---------------------------------------
+// 输入 :
+
+// OpenArchive 以只读方式打开 zip 或 tar 归档，格式根据文件头识别
+// 返回值实现 io/fs.FS，也可以通过 FS() 获取只读的 FileSystem：
+// ar, err := snake.OpenArchive("bundle.zip")
+// defer ar.Close()
+// ar.FS("docs").Ls("*.md")
+// http.Handle("/", http.FileServer(http.FS(ar)))
+func OpenArchive(archive string, opts ...ArchiveReaderOptions) (*ArchiveReader, error) {
+	format, err := detectArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	var opt ArchiveReaderOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	ar := &ArchiveReader{
+		Path:   archive,
+		format: format,
+		file:   f,
+		root:   &archiveNode{path: ".", mode: fs.ModeDir | 0755, children: map[string]*archiveNode{}},
+
+		password: opt.Password,
+	}
+	if format == formatZip {
+		err = ar.indexZip(opt.Charset)
+	} else {
+		err = ar.indexTar()
+	}
+
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return ar, nil
+}
+
+// ---------------------------------------
+// 处理 :
+
+// FS 返回归档内路径的只读 FileSystem，路径相对归档根目录
+// 修改类操作均返回false或 ErrReadOnly。
+func (ar *ArchiveReader) FS(str ...string) FileSystem {
+	sk := &archiveFileSystem{ar: ar, Path: "."}
+	return sk.Add(str...)
+}
+
+// Open 打开归档内的文件或目录，实现 fs.FS
+func (ar *ArchiveReader) Open(name string) (fs.File, error) {
+	node, err := ar.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if node.mode.IsDir() {
+		return &archiveDir{node: node}, nil
+	}
+	return &archiveFile{ar: ar, node: node}, nil
+}
+
+// Stat 返回条目信息，实现 fs.StatFS
+func (ar *ArchiveReader) Stat(name string) (fs.FileInfo, error) {
+	node, err := ar.stat("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// ReadDir 返回目录下按名称排序的条目，实现 fs.ReadDirFS
+func (ar *ArchiveReader) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, err := ar.stat("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return node.entries(), nil
+}
+
+// ReadFile 读取文件全部内容，实现 fs.ReadFileFS
+func (ar *ArchiveReader) ReadFile(name string) ([]byte, error) {
+	f, err := ar.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Close 关闭归档文件
+func (ar *ArchiveReader) Close() error {
+	return ar.file.Close()
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// indexZip 读取 zip 中央目录，非 UTF-8 的名称按charset转换
+func (ar *ArchiveReader) indexZip(charset string) error {
+	info, err := ar.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if ar.zip, err = zip.NewReader(ar.file, info.Size()); err != nil {
+		return err
+	}
+
+	if err := decodeZipNames(ar.zip.File, charset); err != nil {
+		return err
+	}
+
+	for _, file := range ar.zip.File {
+		node := ar.add(file.Name, file.Mode(), file.Modified)
+		if node != nil && !node.mode.IsDir() {
+			node.zip = file
+			node.size = int64(file.UncompressedSize64)
+		}
+	}
+	return nil
+}
+
+// indexTar 顺序读取 tar 条目头，未压缩的 tar 记录内容偏移以便直接读取
+func (ar *ArchiveReader) indexTar() error {
+	raw := &countReader{r: bufio.NewReader(ar.file)}
+	r, err := decompress(raw, ar.format)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// 解压后的字节数即 tar 流中的位置
+	pos := &countReader{r: r}
+	tr := tar.NewReader(pos)
+
+	for i := 0; ; i++ {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		node := ar.add(header.Name, header.FileInfo().Mode(), header.ModTime)
+		if node == nil || node.mode.IsDir() {
+			continue
+		}
+
+		node.index, node.offset, node.size = i, -1, header.Size
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			node.link = header.Linkname
+		case tar.TypeLink:
+			node.hard, node.size = header.Linkname, 0
+		case tar.TypeReg:
+			if ar.format == formatTar && !isSparse(header) {
+				node.offset = pos.n
+			}
+		}
+	}
+
+	// 硬链接读取目标条目的内容
+	for _, node := range ar.nodes() {
+		if node.hard == "" {
+			continue
+		}
+		name, err := entryName(node.hard)
+		if target := ar.node(name); err == nil && target != nil && target.mode.IsRegular() {
+			node.mode = target.mode
+			node.index, node.offset, node.size = target.index, target.offset, target.size
+		}
+	}
+	return nil
+}
+
+// add 添加条目并补全上级目录，越出根目录或与已有文件冲突的条目被忽略
+func (ar *ArchiveReader) add(name string, mode fs.FileMode, modTime time.Time) *archiveNode {
+	clean, err := entryName(name)
+	if err != nil || clean == "." {
+		return nil
+	}
+
+	parent := ar.root
+	parts := strings.Split(clean, "/")
+	for i, part := range parts {
+		child := parent.children[part]
+		last := i == len(parts)-1
+
+		switch {
+		case child == nil:
+			child = &archiveNode{path: path.Join(parent.path, part), mode: fs.ModeDir | 0755}
+			parent.children[part] = child
+		case !last && !child.mode.IsDir():
+			return nil
+		}
+
+		if last {
+			// 同名条目以最后出现的为准，目录保留已有的子条目
+			child.mode, child.modTime = mode, modTime
+			child.link, child.hard, child.zip, child.size = "", "", nil, 0
+		}
+		if child.mode.IsDir() && child.children == nil {
+			child.children = map[string]*archiveNode{}
+		}
+		if !child.mode.IsDir() {
+			child.children = nil
+		}
+		parent = child
+	}
+	return parent
+}
+
+// node 根据清理后的路径查找条目，不解析符号链接
+func (ar *ArchiveReader) node(name string) *archiveNode {
+	node := ar.root
+	for _, part := range splitPath(name) {
+		if node = node.children[part]; node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// nodes 返回所有条目
+func (ar *ArchiveReader) nodes() []*archiveNode {
+	var res []*archiveNode
+	var walk func(n *archiveNode)
+	walk = func(n *archiveNode) {
+		for _, child := range n.children {
+			res = append(res, child)
+			walk(child)
+		}
+	}
+	walk(ar.root)
+	return res
+}
+
+// stat 校验路径并查找条目，解析符号链接
+func (ar *ArchiveReader) stat(op, name string) (*archiveNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	node, err := ar.lookup(name, true)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return node, nil
+}
+
+// lookup 逐级查找条目，follow为true时解析最后一级的符号链接
+// 链接目标为绝对路径或越出归档根目录时视为不存在。
+func (ar *ArchiveReader) lookup(name string, follow bool) (*archiveNode, error) {
+	parts := splitPath(name)
+	node := ar.root
+	links := 0
+
+	for i := 0; i < len(parts); i++ {
+		child := node.children[parts[i]]
+		if child == nil {
+			return nil, fs.ErrNotExist
+		}
+
+		if child.mode&fs.ModeSymlink != 0 && (follow || i < len(parts)-1) {
+			if links++; links > maxSymlinks {
+				return nil, errors.New("too many levels of symbolic links")
+			}
+			target, err := ar.readlink(child)
+			if err != nil {
+				return nil, err
+			}
+
+			next := path.Join(path.Dir(child.path), target)
+			if path.IsAbs(target) || next == ".." || strings.HasPrefix(next, "../") {
+				return nil, fs.ErrNotExist
+			}
+
+			parts = append(splitPath(next), parts[i+1:]...)
+			node, i = ar.root, -1
+			continue
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// readlink 返回符号链接目标，zip 中的目标保存在条目内容中
+func (ar *ArchiveReader) readlink(node *archiveNode) (string, error) {
+	if node.link != "" || node.zip == nil {
+		return node.link, nil
+	}
+
+	f, err := ar.open(node)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(io.LimitReader(f, maxSymlinkTarget))
+	if err != nil {
+		return "", err
+	}
+	node.link = string(b)
+	return node.link, nil
+}
+
+// open 打开条目内容
+func (ar *ArchiveReader) open(node *archiveNode) (io.ReadCloser, error) {
+	switch {
+	case node.zip != nil:
+		return openZipEntry(node.zip, ar.password)
+	case node.offset >= 0:
+		return io.NopCloser(io.NewSectionReader(ar.file, node.offset, node.size)), nil
+	}
+
+	// 压缩的 tar 只能从头顺序读取到该条目
+	f, err := os.Open(ar.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := decompress(bufio.NewReader(f), ar.format)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	tr := tar.NewReader(r)
+	for i := 0; i <= node.index; i++ {
+		if _, err = tr.Next(); err != nil {
+			r.Close()
+			f.Close()
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+
+	return &archiveStream{Reader: tr, closers: []io.Closer{r, f}}, nil
+}
+
+// archiveStream 关闭时依次关闭解压层与文件
+type archiveStream struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (s *archiveStream) Close() error {
+	var err error
+	for _, c := range s.closers {
+		if e := c.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// isSparse 判断是否为稀疏文件，稀疏文件的内容不连续
+func isSparse(header *tar.Header) bool {
+	for k := range header.PAXRecords {
+		if strings.HasPrefix(k, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPath 拆分清理后的路径，根目录返回空
+func splitPath(name string) []string {
+	if name == "." || name == "" {
+		return nil
+	}
+	return strings.Split(name, "/")
+}
+
+// entries 返回按名称排序的子条目
+func (n *archiveNode) entries() []fs.DirEntry {
+	res := make([]fs.DirEntry, 0, len(n.children))
+	for _, child := range n.children {
+		res = append(res, child)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name() < res[j].Name() })
+	return res
+}
+
+// archiveNode 实现 fs.FileInfo 与 fs.DirEntry
+
+func (n *archiveNode) Name() string               { return path.Base(n.path) }
+func (n *archiveNode) Size() int64                { return n.size }
+func (n *archiveNode) Mode() fs.FileMode          { return n.mode }
+func (n *archiveNode) ModTime() time.Time         { return n.modTime }
+func (n *archiveNode) IsDir() bool                { return n.mode.IsDir() }
+func (n *archiveNode) Sys() interface{}           { return nil }
+func (n *archiveNode) Type() fs.FileMode          { return n.mode.Type() }
+func (n *archiveNode) Info() (fs.FileInfo, error) { return n, nil }
+
+// archiveDir 归档中打开的目录
+type archiveDir struct {
+	node *archiveNode
+	list []fs.DirEntry
+	pos  int
+}
+
+func (d *archiveDir) Stat() (fs.FileInfo, error) { return d.node, nil }
+func (d *archiveDir) Close() error               { return nil }
+
+func (d *archiveDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.node.path, Err: errors.New("is a directory")}
+}
+
+// ReadDir 实现 fs.ReadDirFile
+func (d *archiveDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.list == nil {
+		d.list = d.node.entries()
+	}
+
+	rest := d.list[d.pos:]
+	if n <= 0 {
+		d.pos = len(d.list)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.pos += n
+	return rest[:n], nil
+}
+
+// archiveFile 归档中打开的文件，实现 fs.File、io.Seeker 与只读的 FileOperate
+// 向后 Seek 时重新打开条目。
+type archiveFile struct {
+	ar   *ArchiveReader
+	node *archiveNode
+	r    io.ReadCloser
+	pos  int64
+}
+
+func (f *archiveFile) Stat() (fs.FileInfo, error) { return f.node, nil }
+
+func (f *archiveFile) Read(p []byte) (int, error) {
+	if f.r == nil {
+		r, err := f.ar.open(f.node)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := io.CopyN(io.Discard, r, f.pos); err != nil && err != io.EOF {
+			r.Close()
+			return 0, err
+		}
+		f.r = r
+	}
+
+	n, err := f.r.Read(p)
+	f.pos += int64(n)
+	return n, err
+}
+
+// Seek 实现 io.Seeker
+func (f *archiveFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += f.node.size
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.node.path, Err: fs.ErrInvalid}
+	}
+
+	if f.r != nil && offset >= f.pos {
+		if _, err := io.CopyN(io.Discard, f.r, offset-f.pos); err != nil && err != io.EOF {
+			return 0, err
+		}
+	} else if f.r != nil {
+		f.r.Close()
+		f.r = nil
+	}
+	f.pos = offset
+	return offset, nil
+}
+
+func (f *archiveFile) Close() error {
+	if f.r == nil {
+		return nil
+	}
+	err := f.r.Close()
+	f.r = nil
+	return err
+}
+
+// Get 归档中的文件没有对应的 *os.File，返回nil
+func (f *archiveFile) Get() *os.File { return nil }
+
+// Write 归档视图只读
+func (f *archiveFile) Write([]byte) (int, error) { return 0, ErrReadOnly }
+
+// String 读取剩余内容...
+func (f *archiveFile) String() *SnakeString {
+	return String(string(f.Byte()))
+}
+
+// Byte 读取剩余内容 []byte ...
+func (f *archiveFile) Byte() []byte {
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// ReadAt 从off读取，不改变读取位置，每次调用重新打开条目
+func (f *archiveFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "readat", Path: f.node.path, Err: fs.ErrInvalid}
+	}
+
+	r, err := f.ar.open(f.node)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	if _, err := io.CopyN(io.Discard, r, off); err != nil {
+		return 0, err
+	}
+
+	n, err := io.ReadFull(r, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Text 读取剩余内容并转换为 UTF-8，未指定编码时自动检测
+func (f *archiveFile) Text(charset ...string) (string, error) {
+	return readText(f, firstCharset(charset))
+}
+
+// Decode 返回将剩余内容转换为 UTF-8 的 Reader
+func (f *archiveFile) Decode(charset ...string) (io.Reader, error) {
+	return decodeText(f, firstCharset(charset))
+}
+
+// Lines 从当前位置逐行读取，fn返回false时停止
+func (f *archiveFile) Lines(fn func(line string) bool) error {
+	return streamLines(f, fn)
+}
+
+// Chunks 从当前位置按size字节的块读取
+func (f *archiveFile) Chunks(size int, fn func(chunk []byte) bool) error {
+	return readChunks(f, size, fn)
+}
+
+// Tail 返回最后n行，不改变读取位置
+// 归档条目不能高效地随机读取，重新打开条目顺序读取。
+func (f *archiveFile) Tail(n int) ([]string, error) {
+	r, err := f.ar.open(f.node)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return tailStream(r, n)
+}
+
+// ---------------------------------------
+// 只读 FileSystem :
+
+// archiveFileSystem 归档内路径的只读 FileSystem
+type archiveFileSystem struct {
+	ar   *ArchiveReader
+	Path string // 归档内的路径，根目录为 "."
+	err  error  // 路径错误，不为空时所有操作均失败
+}
+
+// Add 在路径中追加文字，绝对路径视为相对归档根目录的路径
+func (sk *archiveFileSystem) Add(str ...string) FileSystem {
+	for _, v := range str {
+		p, err := archivePath(path.Join(sk.Path, String(v).Replace(`\`, "/", true).Get()))
+		if err != nil {
+			sk.err = err
+			return sk
+		}
+		sk.Path = p
+	}
+	return sk
+}
+
+// ReplaceRoot 替换路径的第一级目录
+func (sk *archiveFileSystem) ReplaceRoot(str ...string) FileSystem {
+	parts := strings.Split(sk.Path, "/")
+	parts[0] = str[0]
+	return sk.ar.FS(parts...)
+}
+
+// Dir 获取目录名
+func (sk *archiveFileSystem) Dir() string { return path.Dir(sk.Path) }
+
+// Base 返回路径中最后一个元素
+func (sk *archiveFileSystem) Base() string { return path.Base(sk.Path) }
+
+// Ext 扩展名
+func (sk *archiveFileSystem) Ext() string { return path.Ext(sk.Path) }
+
+// Get 返回归档内的路径
+func (sk *archiveFileSystem) Get() string { return sk.Path }
+
+// Err 返回路径错误
+func (sk *archiveFileSystem) Err() error { return sk.err }
+
+// MimeTypes 根据文件名获取MimeTypes
+func (sk *archiveFileSystem) MimeTypes() string {
+	return mimeTypes[String(sk.Ext()).Trim(".").ToLower().Get()]
+}
+
+// IsDir 判断是否是目录
+func (sk *archiveFileSystem) IsDir(dst ...string) bool {
+	node := sk.stat(dst...)
+	return node != nil && node.mode.IsDir()
+}
+
+// IsFile 判断是否是文件
+func (sk *archiveFileSystem) IsFile(dst ...string) bool {
+	node := sk.stat(dst...)
+	return node != nil && node.mode.IsRegular()
+}
+
+// Exist 判断文件或目录是否存在
+func (sk *archiveFileSystem) Exist(dst ...string) bool {
+	return sk.stat(dst...) != nil
+}
+
+// Ls 返回路径目录下内容，参数与 FileSystem.Ls 相同
+func (sk *archiveFileSystem) Ls(opt ...string) []string {
+	if sk.err != nil {
+		return nil
+	}
+	if len(opt) == 0 {
+		opt = []string{"*"}
+	}
+
+	var res []string
+	for _, v := range opt {
+		if l, err := fs.Glob(sk.ar, path.Join(sk.Path, v)); err == nil {
+			res = append(res, l...)
+		}
+	}
+	return res
+}
+
+// Find 遍历路径下所有目录搜索内容
+func (sk *archiveFileSystem) Find(opt ...string) []string {
+	if sk.err != nil {
+		return nil
+	}
+	if len(opt) == 0 {
+		opt = []string{"*"}
+	}
+
+	var res []string
+	fs.WalkDir(sk.ar, sk.Path, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			for _, v := range opt {
+				if l, err := fs.Glob(sk.ar, path.Join(p, path.Base(v))); len(l) != 0 && err == nil {
+					res = append(res, l...)
+				}
+			}
+		}
+		return err
+	})
+	return res
+}
+
+// Open 打开文件，归档视图不能追加写入
+func (sk *archiveFileSystem) Open(add ...bool) (FileOperate, bool) {
+	node := sk.stat()
+	if node == nil || !node.mode.IsRegular() || (len(add) > 0 && add[0]) {
+		return File(nil), false
+	}
+	return &archiveFile{ar: sk.ar, node: node}, true
+}
+
+// Cp 将归档中的目录或文件拷贝到磁盘dir目录下
+func (sk *archiveFileSystem) Cp(dir string, overwrite bool) bool {
+	if sk.stat() == nil {
+		return false
+	}
+
+	dst := FS(dir).Add(sk.Base())
+	if dst.Exist() && !overwrite {
+		return false
+	}
+
+	ok := true
+	fs.WalkDir(sk.ar, sk.Path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			ok = false
+			return err
+		}
+
+		rel := p
+		if sk.Path != "." {
+			rel = strings.TrimPrefix(strings.TrimPrefix(p, sk.Path), "/")
+		}
+		item := FS(dst.Get(), rel)
+
+		if d.IsDir() {
+			if !item.MkDir() {
+				ok = false
+				return fs.SkipAll
+			}
+			return nil
+		}
+
+		// 符号链接按指向的文件拷贝
+		if node, err := sk.ar.lookup(p, true); err == nil && node.mode.IsRegular() {
+			if !sk.ar.copyTo(node, item) {
+				ok = false
+				return fs.SkipAll
+			}
+		}
+		return nil
+	})
+	return ok
+}
+
+// MD5 获取文件的MD5
+func (sk *archiveFileSystem) MD5() string {
+	return sk.sum(md5.New())
+}
+
+// SHA256 获取文件的SHA256
+func (sk *archiveFileSystem) SHA256() string {
+	return sk.sum(sha256.New())
+}
+
+// Config 加载配置文件，内容先复制到临时文件，格式由扩展名决定
+func (sk *archiveFileSystem) Config(conf interface{}) error {
+	f, ok := sk.Open()
+	if !ok {
+		return fmt.Errorf("snake: open %s in %s failed", sk.Path, sk.ar.Path)
+	}
+	defer f.Close()
+
+	tmp, err := os.CreateTemp("", "snake-*"+sk.Ext())
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, f.(*archiveFile))
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+	return configor.Load(conf, tmp.Name())
+}
+
+// MkDir 归档视图只读
+func (sk *archiveFileSystem) MkDir(dst ...string) bool { return false }
+
+// MkFile 归档视图只读
+func (sk *archiveFileSystem) MkFile(dst ...string) (FileOperate, bool) { return File(nil), false }
+
+// Write 归档视图只读
+func (sk *archiveFileSystem) Write(src string, add ...bool) bool { return false }
+
+// ByteWriter 归档视图只读，返回 ErrReadOnly
+func (sk *archiveFileSystem) ByteWriter(src []byte, add ...bool) (bool, error) {
+	return false, ErrReadOnly
+}
+
+// WriteCharset 归档视图只读，返回 ErrReadOnly
+func (sk *archiveFileSystem) WriteCharset(src, charset string, add ...bool) (bool, error) {
+	return false, ErrReadOnly
+}
+
+// Rm 归档视图只读
+func (sk *archiveFileSystem) Rm(dst ...string) bool { return false }
+
+// Rn 归档视图只读
+func (sk *archiveFileSystem) Rn(newname string) bool { return false }
+
+// Mv 归档视图只读
+func (sk *archiveFileSystem) Mv(newpath string) bool { return false }
+
+// Unzip 归档视图只读，返回 ErrReadOnly
+func (sk *archiveFileSystem) Unzip(opts ...UnzipOptions) (string, error) {
+	return "", ErrReadOnly
+}
+
+// Extract 归档视图只读，返回 ErrReadOnly，拷贝条目到磁盘请使用 Cp
+func (sk *archiveFileSystem) Extract(dst string, opts ...ExtractOptions) error {
+	return ErrReadOnly
+}
+
+// Archive 归档视图只读，返回 ErrReadOnly
+func (sk *archiveFileSystem) Archive(target string, opts ...ArchiveOptions) error {
+	return ErrReadOnly
+}
+
+// List 不支持读取归档中嵌套的归档
+func (sk *archiveFileSystem) List(opts ...ArchiveReaderOptions) ([]ArchiveEntryInfo, error) {
+	return nil, errNestedArchive
+}
+
+// TestArchive 不支持读取归档中嵌套的归档
+func (sk *archiveFileSystem) TestArchive(opts ...ArchiveReaderOptions) ([]EntryCheck, error) {
+	return nil, errNestedArchive
+}
+
+// Manifest 生成归档中当前目录的校验清单，归档视图只读，指定target时返回 ErrReadOnly
+func (sk *archiveFileSystem) Manifest(algo HashAlgo, target ...string) (*Manifest, error) {
+	if len(target) > 0 {
+		return nil, ErrReadOnly
+	}
+
+	node := sk.stat()
+	if node == nil || !node.IsDir() {
+		return nil, sk.notDir()
+	}
+
+	fsys, err := fs.Sub(sk.ar, sk.Path)
+	if err != nil {
+		return nil, err
+	}
+	return hashTree(fsys, algo, "")
+}
+
+// VerifyManifest 根据归档中的清单校验归档中的当前目录，manifest为相对归档根目录的路径
+// 例子：
+// ar.FS().VerifyManifest("SHA256SUMS")
+func (sk *archiveFileSystem) VerifyManifest(manifest string) (*ManifestReport, error) {
+	node := sk.stat()
+	if node == nil || !node.IsDir() {
+		return nil, sk.notDir()
+	}
+
+	file := sk.stat(manifest)
+	if file == nil {
+		return nil, &fs.PathError{Op: "open", Path: manifest, Err: fs.ErrNotExist}
+	}
+
+	body, err := sk.ar.ReadFile(file.path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseManifest(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	fsys, err := fs.Sub(sk.ar, sk.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	skip := file.path
+	if sk.Path != "." {
+		skip = strings.TrimPrefix(file.path, sk.Path+"/")
+	}
+	return verifyTree(fsys, m, skip)
+}
+
+// Snapshot 不支持为归档视图创建快照，可以先通过 Cp 复制到磁盘
+func (sk *archiveFileSystem) Snapshot(repo string, opts ...SnapshotOptions) (*Snapshot, error) {
+	return nil, fmt.Errorf("snake: snapshot of archive: %w", errors.ErrUnsupported)
+}
+
+// Follow 归档中的文件不会追加内容，返回已停止的 Follower
+func (sk *archiveFileSystem) Follow(ctx context.Context, opts ...FollowOptions) *Follower {
+	return stoppedFollower(fmt.Errorf("snake: follow archive entry: %w", errors.ErrUnsupported))
+}
+
+// Transcode 归档视图只读，返回 ErrReadOnly，可以先解压后转换
+func (sk *archiveFileSystem) Transcode(opts ...TranscodeOptions) (*TranscodeReport, error) {
+	return nil, ErrReadOnly
+}
+
+// ConvertNames 归档视图只读，返回 ErrReadOnly
+func (sk *archiveFileSystem) ConvertNames(opts ...NameOptions) (*NameReport, error) {
+	return nil, ErrReadOnly
+}
+
+// Normalize 归档视图只读，返回 ErrReadOnly
+func (sk *archiveFileSystem) Normalize(opts ...NormalizeOptions) (*NormalizeReport, error) {
+	return nil, ErrReadOnly
+}
+
+// notDir 返回当前路径不是目录的错误
+func (sk *archiveFileSystem) notDir() error {
+	if sk.err != nil {
+		return sk.err
+	}
+	return fmt.Errorf("snake: %s is not a directory", sk.Path)
+}
+
+// stat 查找当前路径或dst对应的条目，dst为相对归档根目录的路径
+func (sk *archiveFileSystem) stat(dst ...string) *archiveNode {
+	if sk.err != nil {
+		return nil
+	}
+
+	name := sk.Path
+	if len(dst) > 0 {
+		p, err := archivePath(String(dst[0]).Replace(`\`, "/", true).Get())
+		if err != nil {
+			return nil
+		}
+		name = p
+	}
+
+	node, err := sk.ar.lookup(name, true)
+	if err != nil {
+		return nil
+	}
+	return node
+}
+
+// sum 计算文件内容的哈希值
+func (sk *archiveFileSystem) sum(h hash.Hash) string {
+	f, ok := sk.Open()
+	if !ok {
+		return ""
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f.(*archiveFile)); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// copyTo 将文件内容写入磁盘上的dst
+func (ar *ArchiveReader) copyTo(node *archiveNode, dst FileSystem) bool {
+	src, err := ar.open(node)
+	if err != nil {
+		return false
+	}
+	defer src.Close()
+
+	out, ok := dst.MkFile()
+	if !ok {
+		return false
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err == nil
+}
+
+// archivePath 清理归档内的路径，绝对路径视为相对归档根目录，越出根目录时返回 ErrJailEscape
+func archivePath(p string) (string, error) {
+	clean := strings.TrimLeft(path.Clean(p), "/")
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%w: %s", ErrJailEscape, p)
+	}
+	if clean == "" {
+		return ".", nil
+	}
+	return clean, nil
+}
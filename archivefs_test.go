@@ -0,0 +1,135 @@
+package snake
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// viewEntries 归档视图测试使用的条目，目录 dir 未单独记录，由条目路径补全
+var viewEntries = []testEntry{
+	{name: "a.txt", body: "alpha"},
+	{name: "dir/b.txt", body: "bravo"},
+	{name: "dir/sub/", dir: true},
+	{name: "dir/sub/c.txt", body: strings.Repeat("charlie ", 1000)},
+	{name: "empty.txt"},
+	{name: "link", link: "a.txt"},
+}
+
+func TestArchiveFS(t *testing.T) {
+	for _, ext := range []string{".zip", ".tar", ".tar.gz"} {
+		t.Run(ext, func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), "view"+ext)
+			writeArchive(t, src, viewEntries)
+
+			ar, err := OpenArchive(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ar.Close()
+
+			if err := fstest.TestFS(ar, "a.txt", "dir/b.txt", "dir/sub/c.txt", "empty.txt", "link"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestArchiveFSReadOnly(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(fsys FileSystem) error
+	}{
+		{"write", func(fsys FileSystem) error { return okErr(fsys.Add("a.txt").Write("x")) }},
+		{"append", func(fsys FileSystem) error { return okErr(fsys.Add("a.txt").Write("x", true)) }},
+		{"byte writer", func(fsys FileSystem) error {
+			_, err := fsys.Add("a.txt").ByteWriter([]byte("x"))
+			return err
+		}},
+		{"write charset", func(fsys FileSystem) error {
+			_, err := fsys.Add("a.txt").WriteCharset("x", "GBK")
+			return err
+		}},
+		{"open for append", func(fsys FileSystem) error {
+			_, ok := fsys.Add("a.txt").Open(true)
+			return okErr(ok)
+		}},
+		{"file write", func(fsys FileSystem) error {
+			f, ok := fsys.Add("a.txt").Open()
+			if !ok {
+				return errors.New("open failed")
+			}
+			defer f.Close()
+			_, err := f.Write([]byte("x"))
+			return err
+		}},
+		{"mkdir", func(fsys FileSystem) error { return okErr(fsys.MkDir("new")) }},
+		{"mkfile", func(fsys FileSystem) error {
+			_, ok := fsys.MkFile("new.txt")
+			return okErr(ok)
+		}},
+		{"rm", func(fsys FileSystem) error { return okErr(fsys.Add("a.txt").Rm()) }},
+		{"rn", func(fsys FileSystem) error { return okErr(fsys.Add("a.txt").Rn("z.txt")) }},
+		{"mv", func(fsys FileSystem) error { return okErr(fsys.Add("a.txt").Mv("dir")) }},
+		{"unzip", func(fsys FileSystem) error {
+			_, err := fsys.Unzip()
+			return err
+		}},
+		{"extract", func(fsys FileSystem) error { return fsys.Extract("out") }},
+		{"archive", func(fsys FileSystem) error { return fsys.Archive("out.zip") }},
+		{"manifest target", func(fsys FileSystem) error {
+			_, err := fsys.Manifest(HashSHA256, "SHA256SUMS")
+			return err
+		}},
+		{"transcode", func(fsys FileSystem) error {
+			_, err := fsys.Transcode()
+			return err
+		}},
+		{"convert names", func(fsys FileSystem) error {
+			_, err := fsys.ConvertNames()
+			return err
+		}},
+		{"normalize", func(fsys FileSystem) error {
+			_, err := fsys.Normalize()
+			return err
+		}},
+	}
+
+	for _, ext := range []string{".zip", ".tar.gz"} {
+		src := filepath.Join(t.TempDir(), "view"+ext)
+		writeArchive(t, src, viewEntries)
+		before, _ := os.ReadFile(src)
+
+		ar, err := OpenArchive(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ar.Close()
+
+		for _, tt := range tests {
+			t.Run(ext+"/"+tt.name, func(t *testing.T) {
+				if err := tt.call(ar.FS()); !errors.Is(err, ErrReadOnly) {
+					t.Fatalf("error = %v, want ErrReadOnly", err)
+				}
+				if got, _ := ar.ReadFile("a.txt"); string(got) != "alpha" {
+					t.Fatalf("a.txt = %q after a rejected write", got)
+				}
+			})
+		}
+
+		if after, _ := os.ReadFile(src); string(after) != string(before) {
+			t.Fatalf("%s modified through the archive view", ext)
+		}
+	}
+}
+
+// okErr 将返回bool的写入操作转换为错误，失败时为 ErrReadOnly
+func okErr(ok bool) error {
+	if ok {
+		return nil
+	}
+	return ErrReadOnly
+}
@@ -0,0 +1,269 @@
+package snake
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ErrBlobNotFound 存储中不存在该摘要的内容
+var ErrBlobNotFound = errors.New("snake: blob not found")
+
+// blobTempAge 超过该时间的临时文件在 GC 时被清理
+const blobTempAge = time.Hour
+
+// blobRetries 分片目录被并发删除时 Put 重试的次数
+const blobRetries = 10
+
+// BlobStore 按 SHA-256 摘要寻址的内容存储
+// 内容保存在 <root>/ab/cd/abcd... 中，先写入 <root>/tmp 再重命名，读取时不会看到未写完的内容。
+// 相同内容只保存一份，保存后的文件为只读。
+type BlobStore struct {
+	Root string
+}
+
+// ---------------------------------------
+// 输入 :
+
+// Blobs 打开根目录为root的内容存储，目录在第一次写入时创建
+// 例子：
+// store := snake.Blobs("/var/cache/artifacts")
+// digest, err := store.Put(f)
+func Blobs(root string) *BlobStore {
+	return &BlobStore{Root: filepath.Clean(root)}
+}
+
+// ---------------------------------------
+// 处理 :
+
+// Put 保存r的全部内容，返回十六进制小写的 SHA-256 摘要
+// 内容已存在时不重复保存。
+func (s *BlobStore) Put(r io.Reader) (string, error) {
+	dir := filepath.Join(s.Root, "tmp")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(dir, "blob-*")
+	if err != nil {
+		return "", err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	h := sha256.New()
+	_, err = io.Copy(f, io.TeeReader(r, h))
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return "", err
+	}
+
+	digest := hex.EncodeToString(h.Sum(nil))
+	path := s.path(digest)
+	if FS(path).IsFile() {
+		return digest, nil
+	}
+
+	if err := os.Chmod(tmp, 0444); err != nil {
+		return "", err
+	}
+	// Delete 会删除空的分片目录，可能发生在 MkdirAll 与 Rename 之间，此时重新创建目录后重试
+	for i := 0; ; i++ {
+		if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err == nil {
+			err = os.Rename(tmp, path)
+		}
+		if err == nil || i == blobRetries || !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// 重命名后同步分片目录，保证断电后内容仍然可见；目录不存在说明内容已被并发的 Delete 删除
+	if err := syncDir(filepath.Dir(path)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	return digest, nil
+}
+
+// Get 打开摘要对应的内容，不存在时返回 ErrBlobNotFound
+func (s *BlobStore) Get(digest string) (FileOperate, error) {
+	path, err := s.Path(digest)
+	if err != nil {
+		return File(nil), err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return File(nil), fmt.Errorf("%w: %s", ErrBlobNotFound, digest)
+	} else if err != nil {
+		return File(nil), err
+	}
+	return File(f), nil
+}
+
+// Has 判断摘要对应的内容是否存在
+func (s *BlobStore) Has(digest string) bool {
+	path, err := s.Path(digest)
+	return err == nil && FS(path).IsFile()
+}
+
+// Path 返回摘要对应的文件路径，摘要无效时返回错误
+func (s *BlobStore) Path(digest string) (string, error) {
+	if !validDigest(digest) {
+		return "", fmt.Errorf("snake: invalid blob digest %q", digest)
+	}
+	return s.path(digest), nil
+}
+
+// Delete 删除摘要对应的内容，内容不存在时不返回错误
+func (s *BlobStore) Delete(digest string) error {
+	path, err := s.Path(digest)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// 删除空的分片目录，与之并发的 Put 会重新创建
+	os.Remove(filepath.Dir(path))
+	os.Remove(filepath.Dir(filepath.Dir(path)))
+	return nil
+}
+
+// Verify 重新计算内容的摘要，与文件名不一致时返回错误
+func (s *BlobStore) Verify(digest string) error {
+	path, err := s.Path(digest)
+	if err != nil {
+		return err
+	}
+
+	sum, err := hashFile(os.DirFS(filepath.Dir(path)), digest, HashSHA256)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrBlobNotFound, digest)
+	} else if err != nil {
+		return err
+	}
+
+	if sum != digest {
+		return fmt.Errorf("snake: blob %s is corrupted, content digest is %s", digest, sum)
+	}
+	return nil
+}
+
+// List 返回所有内容的摘要，按摘要排序
+func (s *BlobStore) List() ([]string, error) {
+	var res []string
+	err := s.walk(func(digest string) error {
+		res = append(res, digest)
+		return nil
+	})
+	sort.Strings(res)
+	return res, err
+}
+
+// GC 删除不在roots中的内容，并清理超过1小时的临时文件，返回被删除的摘要
+// roots为仍被引用的摘要，调用方需确保 GC 期间没有新写入且尚未记录引用的内容。
+// 例子：
+// removed, err := store.GC(liveDigests...)
+func (s *BlobStore) GC(roots ...string) ([]string, error) {
+	live := make(map[string]bool, len(roots))
+	for _, v := range roots {
+		live[v] = true
+	}
+
+	var removed []string
+	err := s.walk(func(digest string) error {
+		if live[digest] {
+			return nil
+		}
+		if err := s.Delete(digest); err != nil {
+			return err
+		}
+		removed = append(removed, digest)
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	// 中断的写入留下的临时文件
+	entries, _ := os.ReadDir(filepath.Join(s.Root, "tmp"))
+	for _, v := range entries {
+		if info, err := v.Info(); err == nil && time.Since(info.ModTime()) > blobTempAge {
+			os.Remove(filepath.Join(s.Root, "tmp", v.Name()))
+		}
+	}
+
+	sort.Strings(removed)
+	return removed, nil
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// path 返回摘要对应的分片路径 ab/cd/abcd...
+func (s *BlobStore) path(digest string) string {
+	return filepath.Join(s.Root, digest[:2], digest[2:4], digest)
+}
+
+// walk 遍历分片目录中的所有内容，忽略不符合摘要格式的文件
+func (s *BlobStore) walk(fn func(digest string) error) error {
+	shards, err := filepath.Glob(filepath.Join(s.Root, "[0-9a-f][0-9a-f]", "[0-9a-f][0-9a-f]", "*"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range shards {
+		digest := filepath.Base(path)
+		if !validDigest(digest) || s.path(digest) != path {
+			continue
+		}
+		if err := fn(digest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncDir 将目录中文件的创建与重命名落盘
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	err = f.Sync()
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// validDigest 判断是否为十六进制小写的 SHA-256 摘要
+func validDigest(digest string) bool {
+	if len(digest) != sha256.Size*2 {
+		return false
+	}
+	for i := 0; i < len(digest); i++ {
+		if c := digest[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+package snake
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBlobStore(t *testing.T) {
+	store := Blobs(t.TempDir())
+	body := "blob content"
+	sum := sha256.Sum256([]byte(body))
+	want := hex.EncodeToString(sum[:])
+
+	digest, err := store.Put(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != want {
+		t.Fatalf("digest = %s, want %s", digest, want)
+	}
+	if again, err := store.Put(strings.NewReader(body)); err != nil || again != digest {
+		t.Fatalf("second Put = %s, %v", again, err)
+	}
+
+	path := filepath.Join(store.Root, digest[:2], digest[2:4], digest)
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0444 {
+		t.Fatalf("stored file %v, %v", info, err)
+	}
+
+	f, err := store.Get(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(f)
+	f.Close()
+	if err != nil || string(b) != body {
+		t.Fatalf("Get = %q, %v", b, err)
+	}
+	if !store.Has(digest) {
+		t.Fatal("Has = false after Put")
+	}
+	if err := store.Verify(digest); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := store.List(); err != nil || len(list) != 1 || list[0] != digest {
+		t.Fatalf("List = %v, %v", list, err)
+	}
+
+	if err := store.Delete(digest); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(digest); err != nil {
+		t.Fatalf("Delete of a missing blob: %v", err)
+	}
+	if store.Has(digest) {
+		t.Fatal("Has = true after Delete")
+	}
+	if _, err := store.Get(digest); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("Get error = %v, want ErrBlobNotFound", err)
+	}
+	if FS(filepath.Dir(path)).Exist() {
+		t.Fatal("empty shard directory not removed")
+	}
+}
+
+func TestBlobStoreInvalidDigest(t *testing.T) {
+	store := Blobs(t.TempDir())
+	tests := []struct {
+		name   string
+		digest string
+	}{
+		{"empty", ""},
+		{"short", "abcd"},
+		{"upper", strings.Repeat("A", 64)},
+		{"traversal", "../" + strings.Repeat("a", 61)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := store.Get(tt.digest); err == nil || errors.Is(err, ErrBlobNotFound) {
+				t.Fatalf("Get error = %v", err)
+			}
+			if err := store.Delete(tt.digest); err == nil {
+				t.Fatal("Delete accepted an invalid digest")
+			}
+			if store.Has(tt.digest) {
+				t.Fatal("Has = true")
+			}
+		})
+	}
+}
+
+func TestBlobStoreCorrupted(t *testing.T) {
+	store := Blobs(t.TempDir())
+	digest, err := store.Put(strings.NewReader("original"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path, _ := store.Path(digest)
+	os.Chmod(path, 0644)
+	os.WriteFile(path, []byte("tampered"), 0644)
+	if err := store.Verify(digest); err == nil {
+		t.Fatal("Verify accepted corrupted content")
+	}
+}
+
+func TestBlobStoreGC(t *testing.T) {
+	store := Blobs(t.TempDir())
+	keep, _ := store.Put(strings.NewReader("keep"))
+	drop, _ := store.Put(strings.NewReader("drop"))
+
+	removed, err := store.GC(keep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0] != drop {
+		t.Fatalf("removed = %v, want [%s]", removed, drop)
+	}
+	if !store.Has(keep) || store.Has(drop) {
+		t.Fatal("GC removed the wrong blob")
+	}
+}
+
+// TestBlobStoreConcurrent Delete 删除空分片目录时，并发的 Put 不能失败
+func TestBlobStoreConcurrent(t *testing.T) {
+	store := Blobs(t.TempDir())
+	body := "contended"
+	sum := sha256.Sum256([]byte(body))
+	digest := hex.EncodeToString(sum[:])
+
+	const rounds, workers = 300, 4
+	put := func() error {
+		_, err := store.Put(strings.NewReader(body))
+		return err
+	}
+	del := func() error { return store.Delete(digest) }
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*2)
+	for i := 0; i < workers*2; i++ {
+		fn := put
+		if i%2 == 1 {
+			fn = del
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				if err := fn(); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(strings.NewReader(body)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Verify(digest); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,192 @@
+package snake
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// BOM 字节序标记
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// charsetAliases 常用写法与 IANA 名称的对应关系
+var charsetAliases = map[string]string{
+	"UTF8":      "UTF-8",
+	"UTF-8-BOM": "UTF-8",
+	"UTF8-BOM":  "UTF-8",
+	"UTF16":     "UTF-16LE",
+	"UTF-16":    "UTF-16LE",
+	"UTF16LE":   "UTF-16LE",
+	"UTF16BE":   "UTF-16BE",
+	"SHIFT-JIS": "Shift_JIS",
+	"SJIS":      "Shift_JIS",
+	"CP936":     "GBK",
+	"CP932":     "Shift_JIS",
+	"CP950":     "Big5",
+}
+
+// charsetBOMs 写入时需要添加 BOM 的编码，UTF-16 没有指定字节序时按 Windows 的习惯使用小端序
+var charsetBOMs = map[string][]byte{
+	"UTF-8-BOM": bomUTF8,
+	"UTF8-BOM":  bomUTF8,
+	"UTF16":     bomUTF16LE,
+	"UTF-16":    bomUTF16LE,
+	"UTF-16LE":  bomUTF16LE,
+	"UTF16LE":   bomUTF16LE,
+	"UTF-16BE":  bomUTF16BE,
+	"UTF16BE":   bomUTF16BE,
+}
+
+// ---------------------------------------
+// 处理 :
+
+// WriteCharset 将 UTF-8 文本转换为charset编码后写入文件，add为是否追加写入
+// 支持 GBK、GB18030、Big5、Shift-JIS、UTF-16LE、UTF-16BE、UTF-8-BOM 等，
+// UTF-16 与 UTF-8-BOM 在文件开头写入 BOM，追加到非空文件时不再写入。
+// 文本中有无法用charset表示的字符时返回错误，不写入文件。
+// 例子：
+// snake.FS("export.csv").WriteCharset(text, "GBK")
+func (sk *snakeFileSystem) WriteCharset(src, charset string, add ...bool) (bool, error) {
+	if _, ok := sk.pathdst(); !ok {
+		return false, sk.err
+	}
+
+	body, err := encodeText(src, charset)
+	if err != nil {
+		return false, err
+	}
+
+	bom := charsetBOM(charset)
+	if bom != nil && len(add) > 0 && add[0] {
+		if info, err := os.Stat(sk.Path); err == nil && info.Size() > 0 {
+			bom = nil
+		}
+	}
+	if bom != nil {
+		body = append(append([]byte{}, bom...), body...)
+	}
+	return sk.ByteWriter(body, add...)
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// textEncoding 根据编码名称返回编码，支持 IANA 名称与 Shift-JIS、UTF-16、CP936 等常用写法
+func textEncoding(charset string) (encoding.Encoding, error) {
+	name := strings.ToUpper(strings.TrimSpace(charset))
+	if v, ok := charsetAliases[name]; ok {
+		name = v
+	}
+
+	switch name {
+	case "UTF-8":
+		return unicode.UTF8, nil
+	case "UTF-16LE":
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), nil
+	case "UTF-16BE":
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), nil
+	}
+
+	if enc := getEncoding(name); enc != nil {
+		return enc, nil
+	}
+	return nil, fmt.Errorf("snake: unknown charset %s", charset)
+}
+
+// charsetBOM 返回写入charset编码的文件时需要添加的 BOM，不需要时返回nil
+func charsetBOM(charset string) []byte {
+	return charsetBOMs[strings.ToUpper(strings.TrimSpace(charset))]
+}
+
+// encodeText 将 UTF-8 文本转换为charset编码，不添加 BOM
+func encodeText(src, charset string) ([]byte, error) {
+	enc, err := textEncoding(charset)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := enc.NewEncoder().Bytes([]byte(src))
+	if err != nil {
+		return nil, fmt.Errorf("snake: encode text to %s: %w", charset, err)
+	}
+	return body, nil
+}
+
+// decodeText 返回将r转换为 UTF-8 的 Reader
+// 有 BOM 时根据 BOM 识别编码并去除 BOM；charset为空时根据开头 64KB 的内容检测编码，
+// 检测不到或检测到的编码不支持时原样返回。
+func decodeText(r io.Reader, charset string) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, streamBlock)
+
+	head, _ := br.Peek(len(bomUTF8))
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		br.Discard(len(bomUTF8))
+		return br, nil
+	case bytes.HasPrefix(head, bomUTF16LE):
+		br.Discard(len(bomUTF16LE))
+		charset = "UTF-16LE"
+	case bytes.HasPrefix(head, bomUTF16BE):
+		br.Discard(len(bomUTF16BE))
+		charset = "UTF-16BE"
+	}
+
+	auto := charset == ""
+	if auto {
+		sample, _ := br.Peek(streamBlock)
+		charset = detectCharset(sample, len(sample) == streamBlock)
+	}
+
+	enc, err := textEncoding(charset)
+	if err != nil {
+		if auto {
+			return br, nil
+		}
+		return nil, err
+	}
+	if enc == unicode.UTF8 {
+		return br, nil
+	}
+	return transform.NewReader(br, enc.NewDecoder()), nil
+}
+
+// detectCharset 检测内容的编码，有效的 UTF-8 直接返回
+// truncated 表示内容是截取的开头部分，末尾可能是不完整的字符。
+func detectCharset(sample []byte, truncated bool) string {
+	valid := sample
+	for i := 0; truncated && i < utf8.UTFMax-1 && !utf8.Valid(valid) && len(valid) > 0; i++ {
+		valid = valid[:len(valid)-1]
+	}
+	if utf8.Valid(valid) {
+		return "UTF-8"
+	}
+
+	charset, _ := String(string(sample)).Charset()
+	return charset
+}
+
+// readText 读取r的剩余内容并转换为 UTF-8
+func readText(r io.Reader, charset string) (string, error) {
+	dr, err := decodeText(r, charset)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if _, err := io.Copy(&b, dr); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
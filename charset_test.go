@@ -0,0 +1,181 @@
+package snake
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// encoded 返回text按enc编码后的字节，前面加上bom
+func encoded(t *testing.T, enc encoding.Encoding, bom []byte, text string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(append([]byte{}, bom...), b...)
+}
+
+func TestWriteCharset(t *testing.T) {
+	text := "编号,名称\n1,测试\n"
+	utf16le := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	utf16be := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+
+	tests := []struct {
+		charset string
+		text    string
+		want    []byte
+	}{
+		{"GBK", text, encoded(t, simplifiedchinese.GBK, nil, text)},
+		{"cp936", text, encoded(t, simplifiedchinese.GBK, nil, text)},
+		{"GB18030", text, encoded(t, simplifiedchinese.GB18030, nil, text)},
+		{"Big5", "編號", encoded(t, traditionalchinese.Big5, nil, "編號")},
+		{"Shift-JIS", "日本語", encoded(t, japanese.ShiftJIS, nil, "日本語")},
+		{"UTF-8", text, []byte(text)},
+		{"UTF-8-BOM", text, append([]byte{0xef, 0xbb, 0xbf}, text...)},
+		{"UTF-16", text, encoded(t, utf16le, []byte{0xff, 0xfe}, text)},
+		{"utf-16le", text, encoded(t, utf16le, []byte{0xff, 0xfe}, text)},
+		{"UTF-16BE", text, encoded(t, utf16be, []byte{0xfe, 0xff}, text)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.charset, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.csv")
+			if ok, err := FS(path).WriteCharset(tt.text, tt.charset); !ok {
+				t.Fatal(err)
+			}
+			if b, _ := os.ReadFile(path); !bytes.Equal(b, tt.want) {
+				t.Fatalf("content = % x, want % x", b, tt.want)
+			}
+		})
+	}
+}
+
+// TestWriteCharsetAppend 追加到非空文件时不再写入 BOM
+func TestWriteCharsetAppend(t *testing.T) {
+	utf16le := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	path := filepath.Join(t.TempDir(), "out.txt")
+
+	// 文件不存在时追加写入 BOM
+	if ok, err := FS(path).WriteCharset("a\n", "UTF-16", true); !ok {
+		t.Fatal(err)
+	}
+	if ok, err := FS(path).WriteCharset("b\n", "UTF-16", true); !ok {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); !bytes.Equal(b, encoded(t, utf16le, []byte{0xff, 0xfe}, "a\nb\n")) {
+		t.Fatalf("content = % x", b)
+	}
+
+	// 覆盖写入时重新写入 BOM
+	if ok, err := FS(path).WriteCharset("c\n", "UTF-16"); !ok {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); !bytes.Equal(b, encoded(t, utf16le, []byte{0xff, 0xfe}, "c\n")) {
+		t.Fatalf("content = % x", b)
+	}
+}
+
+func TestWriteCharsetError(t *testing.T) {
+	tests := []struct {
+		charset string
+		text    string
+	}{
+		{"GBK", "emoji 😀"},
+		{"Shift-JIS", "한국어"},
+		{"Big5", "简体"},
+		{"unknown", "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.charset, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.txt")
+			os.WriteFile(path, []byte("old"), 0644)
+			if ok, err := FS(path).WriteCharset(tt.text, tt.charset); ok || err == nil {
+				t.Fatal("WriteCharset succeeded")
+			}
+			if b, _ := os.ReadFile(path); string(b) != "old" {
+				t.Fatalf("file changed to %q", b)
+			}
+		})
+	}
+}
+
+// TestFileText 按指定编码、BOM 或检测到的编码读取
+func TestFileText(t *testing.T) {
+	text := strings.Repeat("这是一段用于检测编码的中文文本，包含常用的汉字。\n", 20)
+
+	tests := []struct {
+		name    string
+		charset string // 写入的编码
+		read    string // 读取时指定的编码，为空时自动识别
+	}{
+		{"gbk", "GBK", "GBK"},
+		{"gbk detected", "GBK", ""},
+		{"gb18030", "GB18030", "GB18030"},
+		{"utf-8", "UTF-8", ""},
+		{"utf-8 bom", "UTF-8-BOM", ""},
+		{"utf-16le bom", "UTF-16LE", ""},
+		{"utf-16be bom", "UTF-16BE", ""},
+		{"bom wins", "UTF-16BE", "GBK"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "in.txt")
+			if ok, err := FS(path).WriteCharset(text, tt.charset); !ok {
+				t.Fatal(err)
+			}
+
+			f, ok := FS(path).Open()
+			if !ok {
+				t.Fatal("Open failed")
+			}
+			defer f.Close()
+			var read []string
+			if tt.read != "" {
+				read = append(read, tt.read)
+			}
+			if got, err := f.Text(read...); err != nil || got != text {
+				t.Fatalf("Text = %q, %v", got, err)
+			}
+
+			f.Seek(0, io.SeekStart)
+			r, err := f.Decode(read...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b, err := io.ReadAll(r); err != nil || string(b) != text {
+				t.Fatalf("Decode = %q, %v", b, err)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "in.txt")
+	os.WriteFile(path, []byte("a"), 0644)
+	f, _ := FS(path).Open()
+	defer f.Close()
+	if _, err := f.Text("unknown"); err == nil {
+		t.Fatal("unknown charset accepted")
+	}
+}
+
+// TestDetectCharset 截取的内容末尾是不完整的 UTF-8 字符时仍识别为 UTF-8
+func TestDetectCharset(t *testing.T) {
+	sample := []byte("中文")
+	if got := detectCharset(sample[:len(sample)-1], true); got != "UTF-8" {
+		t.Fatalf("truncated sample detected as %q", got)
+	}
+	if got := detectCharset([]byte("plain ascii"), false); got != "UTF-8" {
+		t.Fatalf("ascii detected as %q", got)
+	}
+}
@@ -0,0 +1,210 @@
+package snake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsnet/compress/bzip2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Codec tar 归档的压缩方式
+type Codec string
+
+const (
+	CodecNone  Codec = "none"  // 不压缩，.tar
+	CodecGzip  Codec = "gzip"  // .tar.gz、.tgz
+	CodecBzip2 Codec = "bzip2" // .tar.bz2、.tbz2、.tbz
+	CodecXz    Codec = "xz"    // .tar.xz、.txz
+	CodecZstd  Codec = "zstd"  // .tar.zst、.tzst
+)
+
+// TarOptions tar 归档选项
+type TarOptions struct {
+	Codec   Codec // 压缩方式，为空时根据文件扩展名推断，无法推断时使用 bzip2
+	Level   int   // 压缩级别 1~9，zstd 为 1~22，0 时使用各压缩方式的默认级别
+	Threads int   // 并发压缩的线程数，bzip2 与 zstd 支持，0 或 1 时不并发
+
+	Ignore *IgnoreRules // 排除规则，为空时不排除任何条目，见 DefaultIgnore
+
+	Manifest HashAlgo // 设置后在 Close 时写入所有文件条目的校验清单，如 SHA256SUMS
+
+	// 可重现模式：条目在 Close 时按名称排序写入，统一修改时间、属主与权限，
+	// zstd 不再并发压缩，相同内容与选项输出相同字节。
+	Reproducible bool
+	ModTime      time.Time // 可重现模式下的修改时间，为零时使用 SOURCE_DATE_EPOCH，均未设置时为 1980-01-01
+}
+
+// codecExts 扩展名与压缩方式的对应关系，长扩展名在前
+var codecExts = []struct {
+	ext   string
+	codec Codec
+}{
+	{".tar.gz", CodecGzip},
+	{".tgz", CodecGzip},
+	{".tar.bz2", CodecBzip2},
+	{".tbz2", CodecBzip2},
+	{".tbz", CodecBzip2},
+	{".tar.xz", CodecXz},
+	{".txz", CodecXz},
+	{".tar.zst", CodecZstd},
+	{".tzst", CodecZstd},
+	{".tar", CodecNone},
+}
+
+// xzDictCaps xz 各级别对应的字典大小，与 xz 命令的预设一致
+var xzDictCaps = []int{256 << 10, 1 << 20, 2 << 20, 4 << 20, 4 << 20, 8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// codecFromExt 根据文件扩展名推断压缩方式，无法推断时返回空
+func codecFromExt(name string) Codec {
+	lower := strings.ToLower(name)
+	for _, v := range codecExts {
+		if strings.HasSuffix(lower, v.ext) {
+			return v.codec
+		}
+	}
+	return ""
+}
+
+// compressor 返回写入w的压缩层，CodecNone 时返回nil
+func compressor(w io.Writer, opt TarOptions) (io.WriteCloser, error) {
+	switch opt.Codec {
+	case CodecNone:
+		return nil, nil
+	case CodecGzip:
+		level := opt.Level
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	case CodecBzip2:
+		level := opt.Level
+		if level == 0 {
+			level = bzip2.BestCompression
+		}
+		if opt.Threads > 1 {
+			return newBzip2Parallel(w, level, opt.Threads)
+		}
+		return bzip2.NewWriter(w, &bzip2.WriterConfig{Level: level})
+	case CodecXz:
+		conf := xz.WriterConfig{}
+		if opt.Level > 0 && opt.Level < len(xzDictCaps) {
+			conf.DictCap = xzDictCaps[opt.Level]
+		}
+		return conf.NewWriter(w)
+	case CodecZstd:
+		zopts := []zstd.EOption{}
+		if opt.Level > 0 {
+			zopts = append(zopts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(opt.Level)))
+		}
+		if opt.Reproducible {
+			zopts = append(zopts, zstd.WithEncoderConcurrency(1))
+		} else if opt.Threads > 0 {
+			zopts = append(zopts, zstd.WithEncoderConcurrency(opt.Threads))
+		}
+		return zstd.NewWriter(w, zopts...)
+	}
+	return nil, fmt.Errorf("snake: unknown codec %q", opt.Codec)
+}
+
+// bzip2Parallel 将数据分块并发压缩，依次写出为多个连续的 bzip2 流
+// bzip2 工具及 Go 标准库均支持读取连续的多个流。
+type bzip2Parallel struct {
+	w       io.Writer
+	level   int
+	threads int
+	size    int      // 每块大小
+	buf     []byte   // 当前块
+	chunks  [][]byte // 等待压缩的块
+	err     error
+}
+
+func newBzip2Parallel(w io.Writer, level, threads int) (*bzip2Parallel, error) {
+	if level < bzip2.BestSpeed || level > bzip2.BestCompression {
+		return nil, fmt.Errorf("snake: invalid bzip2 level %d", level)
+	}
+	// 每块恰好占用一个 bzip2 数据块
+	size := level * 100000
+	return &bzip2Parallel{w: w, level: level, threads: threads, size: size, buf: make([]byte, 0, size)}, nil
+}
+
+func (p *bzip2Parallel) Write(b []byte) (int, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+
+	n := len(b)
+	for len(b) > 0 {
+		space := p.size - len(p.buf)
+		if space > len(b) {
+			space = len(b)
+		}
+		p.buf = append(p.buf, b[:space]...)
+		b = b[space:]
+
+		if len(p.buf) == p.size {
+			p.chunks = append(p.chunks, p.buf)
+			p.buf = make([]byte, 0, p.size)
+			if len(p.chunks) == p.threads {
+				if err := p.flush(); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+	return n, nil
+}
+
+func (p *bzip2Parallel) Close() error {
+	if p.err != nil {
+		return p.err
+	}
+	if len(p.buf) > 0 {
+		p.chunks = append(p.chunks, p.buf)
+		p.buf = nil
+	}
+	return p.flush()
+}
+
+// flush 并发压缩等待中的块并按顺序写出
+func (p *bzip2Parallel) flush() error {
+	out := make([]bytes.Buffer, len(p.chunks))
+	errs := make([]error, len(p.chunks))
+
+	var wg sync.WaitGroup
+	for i, chunk := range p.chunks {
+		wg.Add(1)
+		go func(i int, chunk []byte) {
+			defer wg.Done()
+			bw, err := bzip2.NewWriter(&out[i], &bzip2.WriterConfig{Level: p.level})
+			if err == nil {
+				_, err = bw.Write(chunk)
+				if e := bw.Close(); err == nil {
+					err = e
+				}
+			}
+			errs[i] = err
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	p.chunks = p.chunks[:0]
+	for i := range out {
+		if p.err = errs[i]; p.err != nil {
+			return p.err
+		}
+		if _, p.err = p.w.Write(out[i].Bytes()); p.err != nil {
+			return p.err
+		}
+	}
+	return nil
+}
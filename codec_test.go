@@ -0,0 +1,114 @@
+package snake
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	// 超过 bzip2 一个数据块的内容，并发压缩时分为多个流
+	body := strings.Repeat("codec round trip ", 40000)
+
+	tests := []struct {
+		opt    TarOptions
+		format archiveFormat
+	}{
+		{TarOptions{Codec: CodecNone}, formatTar},
+		{TarOptions{Codec: CodecGzip}, formatTarGz},
+		{TarOptions{Codec: CodecGzip, Level: 1}, formatTarGz},
+		{TarOptions{Codec: CodecBzip2}, formatTarBz2},
+		{TarOptions{Codec: CodecBzip2, Level: 1, Threads: 4}, formatTarBz2},
+		{TarOptions{Codec: CodecXz}, formatTarXz},
+		{TarOptions{Codec: CodecXz, Level: 1}, formatTarXz},
+		{TarOptions{Codec: CodecZstd}, formatTarZst},
+		{TarOptions{Codec: CodecZstd, Level: 19, Threads: 4}, formatTarZst},
+	}
+
+	for _, tt := range tests {
+		name := fmt.Sprintf("%s/level%d/threads%d", tt.opt.Codec, tt.opt.Level, tt.opt.Threads)
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tl := TarWriter(&buf, tt.opt)
+			if !tl.Add("body.txt", regularHeader("body.txt", int64(len(body))).FileInfo(), []byte(body)) {
+				t.Fatal("Add failed")
+			}
+			if err := tl.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if tl.Codec != tt.opt.Codec {
+				t.Fatalf("Codec = %s", tl.Codec)
+			}
+
+			path := filepath.Join(t.TempDir(), "out")
+			os.WriteFile(path, buf.Bytes(), 0644)
+			if got, err := detectArchive(path); got != tt.format {
+				t.Fatalf("detected format %v, %v, want %v", got, err, tt.format)
+			}
+			r, err := decompress(bytes.NewReader(buf.Bytes()), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			tr := tar.NewReader(r)
+			if _, err := tr.Next(); err != nil {
+				t.Fatal(err)
+			}
+			if b, err := io.ReadAll(tr); err != nil || string(b) != body {
+				t.Fatalf("content mismatch, %v", err)
+			}
+		})
+	}
+}
+
+func TestCodecFromExt(t *testing.T) {
+	tests := []struct {
+		name string
+		want Codec
+	}{
+		{"a.tar", CodecNone},
+		{"a.tar.gz", CodecGzip},
+		{"A.TGZ", CodecGzip},
+		{"a.tar.bz2", CodecBzip2},
+		{"a.tbz", CodecBzip2},
+		{"a.tar.xz", CodecXz},
+		{"a.tar.zst", CodecZstd},
+		{"a.tzst", CodecZstd},
+		{"a.zip", ""},
+		{"a.gz", ""},
+	}
+
+	for _, tt := range tests {
+		if got := codecFromExt(tt.name); got != tt.want {
+			t.Errorf("codecFromExt(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCodecInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  TarOptions
+	}{
+		{"unknown codec", TarOptions{Codec: "lz4"}},
+		{"bzip2 level", TarOptions{Codec: CodecBzip2, Level: 10, Threads: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl := TarWriter(io.Discard, tt.opt)
+			if tl.Add("a.txt", regularHeader("a.txt", 1).FileInfo(), []byte("a")) {
+				t.Fatal("Add succeeded")
+			}
+			if err := tl.Close(); err == nil {
+				t.Fatal("Close succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,605 @@
+package snake
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ArchiveEditor 修改已有的 zip 或 tar 归档
+// 修改先记录在内存中，Save 时写入同目录下的临时文件再替换原归档，出错时原归档不变。
+// zip 中未修改的条目按原始压缩数据复制，不重新压缩；tar 为流式格式，保存时整体重新压缩。
+// 排除规则、可重现模式与校验清单对原有条目同样有效，zip 设置可重现模式或校验清单时原有条目解压后重新写入。
+type ArchiveEditor struct {
+	Path    string // 归档文件路径
+	opt     EditOptions
+	format  archiveFormat
+	file    *os.File
+	zip     *zip.Reader
+	entries []*editEntry
+	err     error
+}
+
+// EditOptions 修改归档的选项
+type EditOptions struct {
+	Zip ZipOptions // 新增及替换的 zip 条目使用的选项，未设置注释时保留原注释
+	Tar TarOptions // tar 重新压缩的选项，未指定压缩方式时沿用原归档的压缩方式
+}
+
+// editEntry 归档中的条目及其修改
+type editEntry struct {
+	name    string      // 当前名称，不含结尾的 /
+	orig    string      // 原名称，与name不同时表示已重命名
+	dir     bool        // 是否为目录
+	index   int         // 原归档中的序号，新增条目为-1
+	zip     *zip.File   // 原 zip 条目
+	header  *tar.Header // 原 tar 条目头
+	deleted bool
+	changed bool        // 内容已替换为body
+	body    []byte      // 替换或新增的内容
+	file    string      // 从磁盘新增的文件或目录
+	info    os.FileInfo // file 的信息
+}
+
+// formatCodecs tar 格式与压缩方式的对应关系
+var formatCodecs = map[archiveFormat]Codec{
+	formatTar:    CodecNone,
+	formatTarGz:  CodecGzip,
+	formatTarBz2: CodecBzip2,
+	formatTarXz:  CodecXz,
+	formatTarZst: CodecZstd,
+}
+
+// ---------------------------------------
+// 输入 :
+
+// EditArchive 打开归档进行修改，格式根据文件头识别
+// 例子：
+// e, err := snake.EditArchive("site.zip")
+// e.Delete("*.map")
+// e.Rename("static", "assets")
+// e.AddFile("assets/app.js", "./dist/app.js")
+// err = e.Save()
+func EditArchive(archive string, opts ...EditOptions) (*ArchiveEditor, error) {
+	format, err := detectArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &ArchiveEditor{Path: archive, format: format, file: f}
+	if len(opts) > 0 {
+		e.opt = opts[0]
+	}
+
+	if format == formatZip {
+		err = e.indexZip()
+	} else {
+		err = e.indexTar()
+	}
+
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// ---------------------------------------
+// 处理 :
+
+// Add 新增文件条目，同名文件已存在时替换其内容
+func (e *ArchiveEditor) Add(name string, body []byte) bool {
+	name, ok := e.name(name)
+	if !ok {
+		return false
+	}
+
+	if entry := e.find(name); entry != nil {
+		if entry.dir {
+			return false
+		}
+		if entry.regular() {
+			entry.replace(body)
+			return true
+		}
+		// 符号链接等替换为普通文件
+		entry.deleted = true
+	}
+
+	e.entries = append(e.entries, &editEntry{name: name, index: -1, changed: true, body: body})
+	return true
+}
+
+// AddFile 将磁盘上的文件或目录新增为name，目录包含其下所有内容
+// 保存时才读取src，同名条目已存在时先删除。
+func (e *ArchiveEditor) AddFile(name, src string) bool {
+	name, ok := e.name(name)
+	if !ok {
+		return false
+	}
+
+	root, err := os.Lstat(src)
+	if err != nil {
+		e.err = err
+		return false
+	}
+
+	e.remove(name)
+
+	if !root.IsDir() {
+		e.entries = append(e.entries, &editEntry{name: name, index: -1, file: src, info: root})
+		return true
+	}
+
+	e.err = filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		e.entries = append(e.entries, &editEntry{
+			name:  path.Join(name, filepath.ToSlash(rel)),
+			dir:   info.IsDir(),
+			index: -1,
+			file:  p,
+			info:  info,
+		})
+		return nil
+	})
+	return e.err == nil
+}
+
+// Replace 替换匹配的文件条目内容，返回替换的条目数
+// pattern 为条目路径或 glob 规则，规则与 ExtractOptions.Include 相同。
+func (e *ArchiveEditor) Replace(pattern string, body []byte) int {
+	n := 0
+	for _, entry := range e.match(pattern) {
+		if entry.dir || !entry.regular() {
+			continue
+		}
+		entry.replace(body)
+		n++
+	}
+	return n
+}
+
+// Delete 删除匹配的条目，匹配的目录连同其下内容一起删除，返回删除的条目数
+func (e *ArchiveEditor) Delete(pattern string) int {
+	n := 0
+	for _, entry := range e.match(pattern) {
+		entry.deleted = true
+		n++
+	}
+	return n
+}
+
+// Rename 将条目或目录oldname重命名为newname，目录下的条目一并移动，返回重命名的条目数
+// newname 已存在时不重命名，返回0。
+func (e *ArchiveEditor) Rename(oldname, newname string) int {
+	oldname, ok := e.name(oldname)
+	if !ok {
+		return 0
+	}
+	if newname, ok = e.name(newname); !ok || e.find(newname) != nil {
+		return 0
+	}
+
+	n := 0
+	for _, entry := range e.entries {
+		if entry.deleted {
+			continue
+		}
+		if entry.name == oldname {
+			entry.name = newname
+			n++
+		} else if strings.HasPrefix(entry.name, oldname+"/") {
+			entry.name = newname + strings.TrimPrefix(entry.name, oldname)
+			n++
+		}
+	}
+	return n
+}
+
+// Save 写入修改后的归档并替换原文件，保存后编辑器关闭
+func (e *ArchiveEditor) Save() error {
+	if e.file == nil {
+		return os.ErrClosed
+	}
+	if e.err != nil {
+		e.Close()
+		return e.err
+	}
+
+	info, err := e.file.Stat()
+	if err != nil {
+		e.Close()
+		return err
+	}
+
+	// 临时文件与原归档位于同一目录，保证 Rename 为原子操作
+	tmp, err := os.CreateTemp(filepath.Dir(e.Path), "."+filepath.Base(e.Path)+".*")
+	if err != nil {
+		e.Close()
+		return err
+	}
+
+	if e.format == formatZip {
+		err = e.saveZip(tmp)
+	} else {
+		err = e.saveTar(tmp)
+	}
+
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := e.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), e.Path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Close 放弃未保存的修改并关闭原归档
+func (e *ArchiveEditor) Close() error {
+	if e.file == nil {
+		return nil
+	}
+	err := e.file.Close()
+	e.file = nil
+	return err
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// indexZip 读取 zip 中央目录
+func (e *ArchiveEditor) indexZip() error {
+	info, err := e.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if e.zip, err = zip.NewReader(e.file, info.Size()); err != nil {
+		return err
+	}
+
+	for i, file := range e.zip.File {
+		name := cleanEntry(file.Name)
+		e.entries = append(e.entries, &editEntry{
+			name:  name,
+			orig:  name,
+			dir:   strings.HasSuffix(file.Name, "/"),
+			index: i,
+			zip:   file,
+		})
+	}
+	return nil
+}
+
+// indexTar 读取 tar 条目头
+func (e *ArchiveEditor) indexTar() error {
+	r, err := decompress(bufio.NewReader(e.file), e.format)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr := tar.NewReader(r)
+	for i := 0; ; i++ {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		name := cleanEntry(header.Name)
+		e.entries = append(e.entries, &editEntry{
+			name:   name,
+			orig:   name,
+			dir:    header.Typeflag == tar.TypeDir,
+			index:  i,
+			header: header,
+		})
+	}
+}
+
+// saveZip 写入 zip，未修改的条目直接复制压缩数据
+func (e *ArchiveEditor) saveZip(w io.Writer) error {
+	opt := e.opt.Zip
+	if opt.Comment == "" {
+		opt.Comment = e.zip.Comment
+	}
+
+	z := ZipWriter(w, opt)
+	for _, entry := range e.entries {
+		if entry.deleted {
+			continue
+		}
+
+		var err error
+		switch {
+		case entry.file != "":
+			err = archiveZipEntry(z, entry.name, entry.info, entry.file)
+		case entry.changed:
+			modOpt := ZipEntryOptions{Modified: time.Now()}
+			if entry.zip != nil {
+				modOpt.Mode, modOpt.Comment = entry.zip.Mode(), entry.zip.Comment
+			}
+			_, err = z.AddReader(entry.name, bytes.NewReader(entry.body), modOpt)
+		default:
+			err = e.copyZip(z, entry)
+		}
+
+		if err != nil {
+			z.Close()
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// copyZip 按原始压缩数据复制 zip 条目，重命名时只修改文件头
+// 可重现模式与校验清单需要条目内容，此时解压后重新写入。
+func (e *ArchiveEditor) copyZip(z *Ziplib, entry *editEntry) error {
+	name := entry.name
+	if entry.dir {
+		name += "/"
+	}
+
+	if rule, ok := z.opt.Ignore.Match(name, entry.dir); ok {
+		z.Skipped = append(z.Skipped, SkippedEntry{Path: name, Rule: rule})
+		return nil
+	}
+	if z.repro != nil || z.sums != nil {
+		return e.rewriteZip(z, entry, name)
+	}
+	if entry.name == entry.orig {
+		return z.FS.Copy(entry.zip)
+	}
+
+	header := entry.zip.FileHeader
+	header.Name = name
+	if !header.NonUTF8 && !isASCII(name) && utf8.ValidString(name) {
+		header.Flags |= flagUTF8
+	}
+
+	w, err := z.FS.CreateRaw(&header)
+	if err != nil {
+		return err
+	}
+
+	r, err := entry.zip.OpenRaw()
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// rewriteZip 解压原 zip 条目后重新写入，保留修改时间、权限、注释与是否压缩
+// 加密的条目使用 EditOptions.Zip.Password 解密。
+func (e *ArchiveEditor) rewriteZip(z *Ziplib, entry *editEntry, name string) error {
+	rc, err := openZipEntry(entry.zip, e.opt.Zip.Password)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	opt := ZipEntryOptions{
+		Modified: entry.zip.Modified,
+		Mode:     entry.zip.Mode(),
+		Method:   MethodDeflate,
+		Comment:  entry.zip.Comment,
+	}
+	if entry.zip.Method == zip.Store {
+		opt.Method = MethodStore
+	}
+	_, err = z.AddReader(name, rc, opt)
+	return err
+}
+
+// saveTar 顺序读取原 tar 并写入修改后的条目，新增的条目写在最后
+func (e *ArchiveEditor) saveTar(w io.Writer) error {
+	opt := e.opt.Tar
+	if opt.Codec == "" {
+		opt.Codec = formatCodecs[e.format]
+	}
+
+	if _, err := e.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r, err := decompress(bufio.NewReader(e.file), e.format)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// 原条目按序号排列，新增的条目没有序号
+	byIndex := map[int]*editEntry{}
+	for _, entry := range e.entries {
+		if entry.index >= 0 {
+			byIndex[entry.index] = entry
+		}
+	}
+
+	t := TarWriter(w, opt)
+	tr := tar.NewReader(r)
+
+	for i := 0; ; i++ {
+		if _, err := tr.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Close()
+			return err
+		}
+
+		if entry := byIndex[i]; !entry.deleted {
+			if err := e.writeTar(t, entry, tr); err != nil {
+				t.Close()
+				return err
+			}
+		}
+	}
+
+	for _, entry := range e.entries {
+		if entry.index < 0 && !entry.deleted {
+			if err := e.writeTar(t, entry, nil); err != nil {
+				t.Close()
+				return err
+			}
+		}
+	}
+	return t.Close()
+}
+
+// writeTar 写入 tar 条目，r为原条目内容
+func (e *ArchiveEditor) writeTar(t *Tarlib, entry *editEntry, r io.Reader) error {
+	if entry.file != "" {
+		return archiveTarEntry(t, entry.name, entry.info, entry.file)
+	}
+
+	var header tar.Header
+	if entry.header != nil {
+		header = *entry.header
+	} else {
+		header = tar.Header{Typeflag: tar.TypeReg, Mode: 0644}
+	}
+
+	if entry.name != entry.orig {
+		header.Name = entry.name
+		if entry.dir {
+			header.Name += "/"
+		}
+	}
+	// 写入时展开稀疏文件，去掉描述稀疏数据的 PAX 记录
+	if header.Typeflag == tar.TypeGNUSparse {
+		header.Typeflag = tar.TypeReg
+	}
+	if len(header.PAXRecords) > 0 {
+		records := make(map[string]string, len(header.PAXRecords))
+		for k, v := range header.PAXRecords {
+			if !strings.HasPrefix(k, "GNU.sparse.") {
+				records[k] = v
+			}
+		}
+		header.PAXRecords = records
+	}
+	if header.Typeflag == tar.TypeLink {
+		header.Linkname = e.rename(header.Linkname)
+	}
+
+	if entry.changed {
+		header.Size = int64(len(entry.body))
+		header.ModTime = time.Now()
+		r = bytes.NewReader(entry.body)
+	}
+
+	if header.Typeflag != tar.TypeReg {
+		header.Size = 0
+	}
+	_, err := t.addHeader(&header, r)
+	return err
+}
+
+// rename 返回原条目重命名后的名称，用于更新硬链接目标
+func (e *ArchiveEditor) rename(name string) string {
+	for _, entry := range e.entries {
+		if entry.header != nil && entry.orig == cleanEntry(name) && entry.name != entry.orig {
+			return entry.name
+		}
+	}
+	return name
+}
+
+// name 清理条目名称，拒绝绝对路径与越出归档根目录的路径
+func (e *ArchiveEditor) name(name string) (string, bool) {
+	clean, err := entryName(name)
+	if err != nil || clean == "." {
+		if err == nil {
+			err = errors.New("snake: empty archive entry name")
+		}
+		e.err = err
+		return "", false
+	}
+	return clean, true
+}
+
+// cleanEntry 返回清理后的条目名称，无效的名称只去掉结尾的 /
+func cleanEntry(name string) string {
+	if clean, err := entryName(name); err == nil {
+		return clean
+	}
+	return strings.TrimSuffix(name, "/")
+}
+
+// find 查找未删除的同名条目
+func (e *ArchiveEditor) find(name string) *editEntry {
+	for _, entry := range e.entries {
+		if !entry.deleted && entry.name == name {
+			return entry
+		}
+	}
+	return nil
+}
+
+// remove 删除名称为name的条目及其下的条目
+func (e *ArchiveEditor) remove(name string) {
+	for _, entry := range e.entries {
+		if entry.name == name || strings.HasPrefix(entry.name, name+"/") {
+			entry.deleted = true
+		}
+	}
+}
+
+// match 返回匹配pattern的未删除条目
+func (e *ArchiveEditor) match(pattern string) []*editEntry {
+	var res []*editEntry
+	for _, entry := range e.entries {
+		if !entry.deleted && matchPath([]string{pattern}, entry.name) {
+			res = append(res, entry)
+		}
+	}
+	return res
+}
+
+// replace 替换条目内容
+func (entry *editEntry) replace(body []byte) {
+	entry.changed, entry.body, entry.file, entry.info = true, body, "", nil
+}
+
+// regular 判断条目是否为普通文件
+func (entry *editEntry) regular() bool {
+	switch {
+	case entry.zip != nil:
+		return entry.zip.Mode().IsRegular()
+	case entry.header != nil:
+		return entry.header.Typeflag == tar.TypeReg
+	}
+	return true
+}
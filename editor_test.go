@@ -0,0 +1,306 @@
+package snake
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// editEntries 修改测试使用的原始条目
+var editEntries = []testEntry{
+	{name: "a.txt", body: "a"},
+	{name: "dir/", dir: true},
+	{name: "dir/b.txt", body: "b"},
+	{name: "dir/c.log", body: "c"},
+}
+
+// writeArchive 按扩展名生成 zip 或 tar.gz 归档
+func writeArchive(t *testing.T, path string, entries []testEntry) {
+	t.Helper()
+	if strings.HasSuffix(path, ".zip") {
+		writeZip(t, path, entries)
+	} else {
+		writeTar(t, path, strings.HasSuffix(path, ".gz"), entries)
+	}
+}
+
+// archiveFiles 返回归档中所有文件条目的内容
+func archiveFiles(t *testing.T, path string) map[string]string {
+	t.Helper()
+	ar, err := OpenArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ar.Close()
+
+	res := map[string]string{}
+	err = fs.WalkDir(ar, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := ar.ReadFile(name)
+		res[name] = string(b)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestEditArchive(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(e *ArchiveEditor) int
+		want map[string]string
+	}{
+		{
+			name: "add",
+			edit: func(e *ArchiveEditor) int {
+				if !e.Add("dir/new.txt", []byte("new")) {
+					return 0
+				}
+				return 1
+			},
+			want: map[string]string{"a.txt": "a", "dir/b.txt": "b", "dir/c.log": "c", "dir/new.txt": "new"},
+		},
+		{
+			name: "replace",
+			edit: func(e *ArchiveEditor) int { return e.Replace("dir/*.txt", []byte("replaced")) },
+			want: map[string]string{"a.txt": "a", "dir/b.txt": "replaced", "dir/c.log": "c"},
+		},
+		{
+			name: "delete",
+			edit: func(e *ArchiveEditor) int { return e.Delete("dir") },
+			want: map[string]string{"a.txt": "a"},
+		},
+		{
+			name: "rename",
+			edit: func(e *ArchiveEditor) int { return e.Rename("dir", "lib") },
+			want: map[string]string{"a.txt": "a", "lib/b.txt": "b", "lib/c.log": "c"},
+		},
+	}
+
+	for _, ext := range []string{".zip", ".tar.gz"} {
+		for _, tt := range tests {
+			t.Run(ext+"/"+tt.name, func(t *testing.T) {
+				src := filepath.Join(t.TempDir(), "site"+ext)
+				writeArchive(t, src, editEntries)
+
+				e, err := EditArchive(src)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n := tt.edit(e); n == 0 {
+					t.Fatal("edit changed no entries")
+				}
+				if err := e.Save(); err != nil {
+					t.Fatal(err)
+				}
+
+				if got := archiveFiles(t, src); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("entries = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestEditArchiveOptions(t *testing.T) {
+	for _, ext := range []string{".zip", ".tar.gz"} {
+		t.Run(ext+"/ignore", func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), "site"+ext)
+			writeArchive(t, src, editEntries)
+
+			rules := Ignore("*.log")
+			e, err := EditArchive(src, EditOptions{Zip: ZipOptions{Ignore: rules}, Tar: TarOptions{Ignore: rules}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			e.Add("new.log", []byte("new"))
+			if err := e.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			want := map[string]string{"a.txt": "a", "dir/b.txt": "b"}
+			if got := archiveFiles(t, src); !reflect.DeepEqual(got, want) {
+				t.Fatalf("entries = %v, want %v", got, want)
+			}
+		})
+
+		t.Run(ext+"/reproducible", func(t *testing.T) {
+			dir := t.TempDir()
+			reversed := make([]testEntry, len(editEntries))
+			for i, v := range editEntries {
+				reversed[len(editEntries)-1-i] = v
+			}
+
+			var outputs [][]byte
+			for i, entries := range [][]testEntry{editEntries, reversed} {
+				src := filepath.Join(dir, fmt.Sprintf("site%d%s", i, ext))
+				writeArchive(t, src, entries)
+
+				opt := EditOptions{Zip: ZipOptions{Reproducible: true}, Tar: TarOptions{Reproducible: true}}
+				e, err := EditArchive(src, opt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				e.Add("new.txt", []byte("new"))
+				e.Rename("dir/b.txt", "dir/z.txt")
+				if err := e.Save(); err != nil {
+					t.Fatal(err)
+				}
+
+				b, _ := os.ReadFile(src)
+				outputs = append(outputs, b)
+			}
+			if !bytes.Equal(outputs[0], outputs[1]) {
+				t.Fatal("reproducible edits of the same entries differ")
+			}
+		})
+
+		t.Run(ext+"/manifest", func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), "site"+ext)
+			writeArchive(t, src, editEntries)
+
+			opt := EditOptions{Zip: ZipOptions{Manifest: HashSHA256}, Tar: TarOptions{Manifest: HashSHA256}}
+			e, err := EditArchive(src, opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e.Replace("a.txt", []byte("changed"))
+			if err := e.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			ar, err := OpenArchive(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ar.Close()
+			report, err := ar.FS().VerifyManifest("SHA256SUMS")
+			if err != nil {
+				t.Fatalf("VerifyManifest: %v, %+v", err, report)
+			}
+		})
+	}
+}
+
+func TestEditTarSparse(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "sparse.tar")
+	os.WriteFile(src, sparseTar("sparse.bin", "hello", 4096), 0644)
+
+	e, err := EditArchive(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Add("new.txt", []byte("new"))
+	if err := e.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	found := false
+	tr := tar.NewReader(f)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if header.Name != "sparse.bin" {
+			continue
+		}
+
+		found = true
+		if header.Typeflag != tar.TypeReg || header.Size != 4096 {
+			t.Fatalf("header = %c %d, want a 4096 byte regular file", header.Typeflag, header.Size)
+		}
+		for k := range header.PAXRecords {
+			if strings.HasPrefix(k, "GNU.sparse.") {
+				t.Fatalf("sparse PAX record %s kept", k)
+			}
+		}
+		b, _ := io.ReadAll(tr)
+		if want := "hello" + strings.Repeat("\x00", 4091); string(b) != want {
+			t.Fatal("sparse content not expanded")
+		}
+	}
+	if !found {
+		t.Fatal("sparse.bin missing")
+	}
+}
+
+// sparseTar 生成 PAX 1.0 格式的稀疏文件，内容为body后跟空洞，总大小为size
+// tar.Writer 不能写入稀疏文件，条目头手工生成。
+func sparseTar(name, body string, size int) []byte {
+	var pax string
+	for _, v := range [][2]string{
+		{"GNU.sparse.major", "1"},
+		{"GNU.sparse.minor", "0"},
+		{"GNU.sparse.name", name},
+		{"GNU.sparse.realsize", strconv.Itoa(size)},
+	} {
+		pax += paxRecord(v[0], v[1])
+	}
+	sparseMap := fmt.Sprintf("1\n0\n%d\n", len(body))
+
+	var buf bytes.Buffer
+	buf.Write(tarBlock("PaxHeaders/"+name, tar.TypeXHeader, len(pax)))
+	buf.Write(tarPad(pax))
+	buf.Write(tarBlock(name, tar.TypeReg, 512+len(body)))
+	buf.Write(tarPad(sparseMap))
+	buf.Write(tarPad(body))
+	buf.Write(make([]byte, 1024))
+	return buf.Bytes()
+}
+
+// tarBlock 生成类型为flag的 ustar 条目头
+func tarBlock(name string, flag byte, size int) []byte {
+	var buf bytes.Buffer
+	tar.NewWriter(&buf).WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(size), Format: tar.FormatUSTAR})
+	b := buf.Bytes()[:512]
+
+	// 修改类型后重新计算校验和，校验和字段按空格计算
+	b[156] = flag
+	copy(b[148:156], "        ")
+	sum := 0
+	for _, c := range b {
+		sum += int(c)
+	}
+	copy(b[148:156], fmt.Sprintf("%06o\x00 ", sum))
+	return b
+}
+
+// tarPad 将内容补齐到 512 字节的整数倍
+func tarPad(s string) []byte {
+	b := []byte(s)
+	if n := len(b) % 512; n > 0 {
+		b = append(b, make([]byte, 512-n)...)
+	}
+	return b
+}
+
+// paxRecord 生成 PAX 扩展头记录，长度包含长度字段本身
+func paxRecord(k, v string) string {
+	s := " " + k + "=" + v + "\n"
+	n := len(s)
+	for n != len(s)+len(strconv.Itoa(n)) {
+		n = len(s) + len(strconv.Itoa(n))
+	}
+	return strconv.Itoa(n) + s
+}
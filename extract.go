@@ -0,0 +1,527 @@
+package snake
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// ExtractOptions 解压选项
+type ExtractOptions struct {
+	UnzipOptions                    // 安全限制，与 Unzip 相同
+	StripComponents int             // 去掉条目路径开头的层级数
+	Include         []string        // 只解压匹配的条目，为空时解压全部
+	Exclude         []string        // 跳过匹配的条目
+	Overwrite       OverwritePolicy // 目标已存在时的处理方式
+}
+
+// OverwritePolicy 目标文件已存在时的处理方式
+type OverwritePolicy int
+
+const (
+	OverwriteAll   OverwritePolicy = iota // 覆盖已存在的文件
+	OverwriteNever                        // 跳过已存在的文件
+	OverwriteNewer                        // 条目比已存在的文件新时覆盖
+	OverwriteError                        // 已存在时返回 *UnzipError
+)
+
+// archiveFormat 归档格式
+type archiveFormat string
+
+const (
+	formatZip    archiveFormat = "zip"
+	formatTar    archiveFormat = "tar"
+	formatTarGz  archiveFormat = "tar.gz"
+	formatTarBz2 archiveFormat = "tar.bz2"
+	formatTarXz  archiveFormat = "tar.xz"
+	formatTarZst archiveFormat = "tar.zst"
+)
+
+// ErrUnknownArchive 无法识别的归档格式
+var ErrUnknownArchive = errors.New("snake: unknown archive format")
+
+// archiveMagic 各格式文件头，tar 的 ustar 标识位于第257字节
+var archiveMagic = []struct {
+	format archiveFormat
+	offset int
+	magic  []byte
+}{
+	{formatZip, 0, []byte("PK\x03\x04")},
+	{formatZip, 0, []byte("PK\x05\x06")},
+	{formatTarGz, 0, []byte{0x1f, 0x8b}},
+	{formatTarBz2, 0, []byte("BZh")},
+	{formatTarXz, 0, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{formatTarZst, 0, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{formatTar, 257, []byte("ustar")},
+}
+
+// archiveEntry 归档中的一个条目
+type archiveEntry struct {
+	name    string
+	mode    os.FileMode // 包含目录、符号链接等类型位
+	modTime time.Time
+	link    string // 符号链接或硬链接的目标
+	hard    bool   // 是否为硬链接
+}
+
+// extractor 将归档条目安全地写入目标目录
+type extractor struct {
+	opt   ExtractOptions
+	dst   *snakeFileSystem // 以目标目录为根的 Jail
+	total int64            // 已写入的字节数
+	files int              // 已处理的条目数
+	raw   *countReader     // 流式格式已读取的压缩数据，用于边写入边检查整体压缩比
+	links []string         // 已创建的符号链接，解压完成后重新校验
+}
+
+// ---------------------------------------
+// 处理 :
+
+// Extract 解压归档文件到dst目录
+// 根据文件头而非扩展名识别格式，支持 zip、tar 以及 gzip、bzip2、xz、zstd 压缩的 tar。
+// 与 Unzip 相同，条目路径越出dst或超过安全限制时返回 *UnzipError；
+// tar 为流式格式，出错前已解压的条目会保留在dst中。
+// 通过 DryRun 计划调用时只记录操作；在事务中先解压到日志目录，完成后移动到dst，dst已存在时返回错误。
+// 例子：
+// snake.FS("release.tar.xz").Extract("./release", snake.ExtractOptions{StripComponents: 1})
+func (sk *snakeFileSystem) Extract(dst string, opts ...ExtractOptions) error {
+	src, ok := sk.pathdst()
+	if !ok {
+		return sk.err
+	}
+
+	if dst, ok = sk.pathdst(dst); !ok {
+		return sk.err
+	}
+
+	if sk.plan != nil {
+		return sk.plan.extract(src, dst, opts...)
+	}
+
+	var opt ExtractOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	opt.UnzipOptions = unzipOptions(opt.UnzipOptions)
+
+	format, err := detectArchive(src)
+	if err != nil {
+		return err
+	}
+
+	if sk.tx != nil {
+		return sk.tx.extract(dst, func(dir string) error { return extractTo(src, dir, format, opt) })
+	}
+
+	if !FS(dst).MkDir() {
+		return fmt.Errorf("snake: create directory %s failed", dst)
+	}
+	return extractTo(src, dst, format, opt)
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// extractTo 将归档解压到已存在的dst目录
+func extractTo(src, dst string, format archiveFormat, opt ExtractOptions) error {
+	e := &extractor{opt: opt, dst: newJail(dst)}
+	if e.dst.err != nil {
+		return e.dst.err
+	}
+
+	var err error
+	if format == formatZip {
+		err = e.zip(src)
+	} else {
+		err = e.tar(src, format)
+	}
+	if err != nil {
+		return err
+	}
+	return e.verifyLinks()
+}
+
+// detectArchive 根据文件头识别归档格式
+func detectArchive(path string) (archiveFormat, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	head = head[:n]
+
+	for _, v := range archiveMagic {
+		if len(head) >= v.offset+len(v.magic) && bytes.Equal(head[v.offset:v.offset+len(v.magic)], v.magic) {
+			return v.format, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnknownArchive, path)
+}
+
+// decompress 根据格式返回解压后的 tar 数据流
+func decompress(r io.Reader, format archiveFormat) (io.ReadCloser, error) {
+	switch format {
+	case formatTar:
+		return io.NopCloser(r), nil
+	case formatTarGz:
+		return gzip.NewReader(r)
+	case formatTarBz2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case formatTarXz:
+		x, err := xz.NewReader(r)
+		return io.NopCloser(x), err
+	case formatTarZst:
+		z, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return z.IOReadCloser(), nil
+	}
+	return nil, ErrUnknownArchive
+}
+
+// tar 流式解压 tar 归档
+func (e *extractor) tar(src string, format archiveFormat) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// 统计读取的压缩数据量，用于计算整体压缩比
+	e.raw = &countReader{r: bufio.NewReader(f)}
+	r, err := decompress(e.raw, format)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		entry := archiveEntry{
+			name:    header.Name,
+			mode:    header.FileInfo().Mode(),
+			modTime: header.ModTime,
+			link:    header.Linkname,
+			hard:    header.Typeflag == tar.TypeLink,
+		}
+
+		if err := e.entry(entry, func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }, -1); err != nil {
+			return err
+		}
+	}
+}
+
+// entry 解压单个条目，compressed为条目压缩后的大小，未知时为-1
+func (e *extractor) entry(entry archiveEntry, open func() (io.ReadCloser, error), compressed int64) error {
+	name, err := entryName(entry.name)
+	if err != nil {
+		return err
+	}
+
+	e.files++
+	if e.opt.MaxFiles > 0 && e.files > e.opt.MaxFiles {
+		return &UnzipError{Entry: entry.name, Rule: RuleMaxFiles}
+	}
+
+	if name = e.opt.strip(name); name == "" || !e.opt.match(name) {
+		return nil
+	}
+
+	item := filepath.Join(e.dst.root, filepath.FromSlash(name))
+	if _, err := e.dst.resolve(item); err != nil {
+		return &UnzipError{Entry: entry.name, Rule: RulePathTraversal}
+	}
+
+	// 如果是目录，则创建目录
+	if entry.mode.IsDir() {
+		return os.MkdirAll(item, os.ModePerm)
+	}
+
+	// 设备文件、管道等不解压
+	if !entry.mode.IsRegular() && entry.mode&os.ModeSymlink == 0 && !entry.hard {
+		return nil
+	}
+
+	if entry.mode&os.ModeSymlink != 0 && e.opt.SkipSymlinks {
+		return nil
+	}
+
+	if skip, err := e.exists(entry, item); skip || err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(item), os.ModePerm); err != nil {
+		return err
+	}
+
+	switch {
+	case entry.mode&os.ModeSymlink != 0:
+		return e.symlink(entry, open, item)
+	case entry.hard:
+		return e.hardlink(entry, item)
+	}
+
+	// 获取到 Reader
+	f, err := open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	out, err := os.OpenFile(item, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, entry.mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	// 声明的大小可能被篡改，按实际字节数限制
+	var r io.Reader = f
+	limit := e.limit(compressed)
+	if limit >= 0 {
+		r = io.LimitReader(f, limit+1)
+	}
+
+	// 流式格式不知道条目压缩后的大小，写入时按整体压缩比检查，不等整个条目写完
+	var w io.Writer = out
+	if compressed < 0 && e.raw != nil && e.opt.MaxRatio > 0 {
+		w = &ratioWriter{w: out, e: e, entry: entry.name}
+	}
+
+	n, err := io.Copy(w, r)
+	out.Close()
+	e.total += n
+
+	if err == nil && limit >= 0 && n > limit {
+		err = &UnzipError{Entry: entry.name, Rule: RuleMaxRatio}
+		if e.opt.MaxSize > 0 && e.total > e.opt.MaxSize {
+			err = &UnzipError{Entry: entry.name, Rule: RuleMaxSize}
+		}
+	}
+
+	if err != nil {
+		// 不保留超过限制的部分内容
+		var ue *UnzipError
+		if errors.As(err, &ue) {
+			os.Remove(item)
+		}
+		return err
+	}
+
+	if !entry.modTime.IsZero() {
+		os.Chtimes(item, entry.modTime, entry.modTime)
+	}
+
+	return nil
+}
+
+// limit 返回当前条目允许写入的最大字节数，不限制时返回-1
+func (e *extractor) limit(compressed int64) int64 {
+	limit := int64(-1)
+	if e.opt.MaxSize > 0 {
+		limit = e.opt.MaxSize - e.total
+	}
+	if e.opt.MaxRatio > 0 && compressed >= 0 {
+		l := compressed * e.opt.MaxRatio
+		if l < minRatioSize {
+			l = minRatioSize
+		}
+		if limit < 0 || l < limit {
+			limit = l
+		}
+	}
+	return limit
+}
+
+// exists 根据覆盖策略处理已存在的目标，返回是否跳过该条目
+func (e *extractor) exists(entry archiveEntry, item string) (bool, error) {
+	info, err := os.Lstat(item)
+	if err != nil {
+		return false, nil
+	}
+
+	switch e.opt.Overwrite {
+	case OverwriteNever:
+		return true, nil
+	case OverwriteNewer:
+		if !entry.modTime.After(info.ModTime()) {
+			return true, nil
+		}
+	case OverwriteError:
+		return false, &UnzipError{Entry: entry.name, Rule: RuleExists}
+	}
+
+	// 先删除已存在的链接与文件，避免写入链接指向的位置
+	if !info.IsDir() {
+		return false, os.Remove(item)
+	}
+	return false, nil
+}
+
+// symlink 创建指向目标目录内的符号链接
+func (e *extractor) symlink(entry archiveEntry, open func() (io.ReadCloser, error), item string) error {
+	target := entry.link
+	if target == "" {
+		// zip 中符号链接的目标保存在条目内容中
+		f, err := open()
+		if err != nil {
+			return err
+		}
+		b, err := io.ReadAll(io.LimitReader(f, maxSymlinkTarget+1))
+		f.Close()
+		if err != nil {
+			return err
+		}
+		target = string(b)
+	}
+
+	link := filepath.FromSlash(target)
+	if len(target) > maxSymlinkTarget || filepath.IsAbs(link) || filepath.VolumeName(link) != "" {
+		return &UnzipError{Entry: entry.name, Rule: RuleSymlink}
+	}
+
+	// 目标原样交给解析器，不能先 Join 消去 ..，p/.. 在 p 为符号链接时不等于 .
+	if !e.within(filepath.Dir(item) + string(filepath.Separator) + link) {
+		return &UnzipError{Entry: entry.name, Rule: RuleSymlink}
+	}
+
+	if err := os.Symlink(link, item); err != nil {
+		return err
+	}
+	e.links = append(e.links, item)
+	return nil
+}
+
+// hardlink 创建指向已解压条目的硬链接
+func (e *extractor) hardlink(entry archiveEntry, item string) error {
+	name, err := entryName(entry.link)
+	if err != nil {
+		return &UnzipError{Entry: entry.name, Rule: RuleSymlink}
+	}
+
+	// 目标必须是普通文件本身，os.Link 不跟随符号链接，链接到符号链接会把相对目标带到新位置
+	target := filepath.Join(e.dst.root, filepath.FromSlash(e.opt.strip(name)))
+	if _, err := e.dst.resolve(target); err != nil {
+		return &UnzipError{Entry: entry.name, Rule: RuleSymlink}
+	}
+	if info, err := os.Lstat(target); err != nil || !info.Mode().IsRegular() {
+		return &UnzipError{Entry: entry.name, Rule: RuleSymlink}
+	}
+
+	return os.Link(target, item)
+}
+
+// within 解析路径中的全部符号链接，判断真实路径是否位于目标目录内
+func (e *extractor) within(path string) bool {
+	real, err := realPath(path)
+	return err == nil && isWithin(e.dst.root, real)
+}
+
+// verifyLinks 解压完成后重新校验符号链接
+// 后解压的符号链接可能改变先前链接目标中路径的含义，越出目标目录的链接被删除。
+func (e *extractor) verifyLinks() error {
+	var err error
+	for _, link := range e.links {
+		if e.within(link) {
+			continue
+		}
+		os.Remove(link)
+		if err == nil {
+			rel, _ := filepath.Rel(e.dst.root, link)
+			err = &UnzipError{Entry: filepath.ToSlash(rel), Rule: RuleSymlink}
+		}
+	}
+	return err
+}
+
+// strip 去掉路径开头的层级，层级不足时返回空
+func (opt ExtractOptions) strip(name string) string {
+	if opt.StripComponents <= 0 {
+		return name
+	}
+	parts := strings.Split(name, "/")
+	if len(parts) <= opt.StripComponents {
+		return ""
+	}
+	return path.Join(parts[opt.StripComponents:]...)
+}
+
+// match 判断条目是否满足 Include 与 Exclude 规则
+func (opt ExtractOptions) match(name string) bool {
+	if len(opt.Include) > 0 && !matchPath(opt.Include, name) {
+		return false
+	}
+	return !matchPath(opt.Exclude, name)
+}
+
+// matchPath 判断路径、路径的任一上级目录或文件名是否匹配规则
+func matchPath(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+		if ok, _ := path.Match(pattern, path.Base(name)); ok {
+			return true
+		}
+		for p := name; p != "." && p != "/"; p = path.Dir(p) {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ratioWriter 写入时检查已解压的总大小与已读取的压缩数据之比
+type ratioWriter struct {
+	w     io.Writer
+	e     *extractor
+	entry string
+	n     int64 // 当前条目已写入的字节数
+}
+
+func (w *ratioWriter) Write(p []byte) (int, error) {
+	total := w.e.total + w.n + int64(len(p))
+	if total > minRatioSize && w.e.opt.exceedRatio(uint64(total), uint64(w.e.raw.n)) {
+		return 0, &UnzipError{Entry: w.entry, Rule: RuleMaxRatio}
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// countReader 统计读取的字节数
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,208 @@
+package snake
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testEntry 测试归档中的条目
+type testEntry struct {
+	name string
+	body string
+	link string // 符号链接的目标
+	hard string // 硬链接的目标，只用于 tar
+	dir  bool
+}
+
+// writeTar 生成 tar 归档，gz为true时使用 gzip 压缩
+func writeTar(t *testing.T, path string, gz bool, entries []testEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var zw *gzip.Writer
+	if gz {
+		zw = gzip.NewWriter(&buf)
+		w = zw
+	}
+
+	tw := tar.NewWriter(w)
+	for _, v := range entries {
+		h := &tar.Header{Name: v.name, Mode: 0644, Size: int64(len(v.body)), Typeflag: tar.TypeReg}
+		switch {
+		case v.dir:
+			h.Typeflag, h.Mode, h.Size = tar.TypeDir, 0755, 0
+		case v.link != "":
+			h.Typeflag, h.Linkname, h.Size = tar.TypeSymlink, v.link, 0
+		case v.hard != "":
+			h.Typeflag, h.Linkname, h.Size = tar.TypeLink, v.hard, 0
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Size > 0 {
+			tw.Write([]byte(v.body))
+		}
+	}
+	tw.Close()
+	if zw != nil {
+		zw.Close()
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeZip 生成 zip 归档，符号链接的目标保存在条目内容中
+func writeZip(t *testing.T, path string, entries []testEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, v := range entries {
+		h := &zip.FileHeader{Name: v.name, Method: zip.Deflate}
+		body := v.body
+		switch {
+		case v.dir:
+			h.SetMode(os.ModeDir | 0755)
+		case v.link != "":
+			h.SetMode(os.ModeSymlink | 0777)
+			body = v.link
+		default:
+			h.SetMode(0644)
+		}
+		f, err := zw.CreateHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(f, body)
+	}
+	zw.Close()
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// unzipRule 返回错误中违反的规则
+func unzipRule(err error) UnzipRule {
+	var ue *UnzipError
+	if errors.As(err, &ue) {
+		return ue.Rule
+	}
+	return ""
+}
+
+func TestExtractZipSlip(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []testEntry
+		rule    UnzipRule // 为空时应解压成功
+		zip     bool      // 是否同时测试 zip 格式
+		absent  []string  // 不应存在的路径，相对解压目录
+	}{
+		{name: "dotdot", entries: []testEntry{{name: "../evil", body: "x"}}, rule: RulePathTraversal, zip: true},
+		{name: "nested dotdot", entries: []testEntry{{name: "a/../../evil", body: "x"}}, rule: RulePathTraversal, zip: true},
+		{name: "backslash dotdot", entries: []testEntry{{name: `..\evil`, body: "x"}}, rule: RulePathTraversal, zip: true},
+		{name: "absolute", entries: []testEntry{{name: "/evil", body: "x"}}, rule: RuleAbsolutePath, zip: true},
+		{name: "drive letter", entries: []testEntry{{name: "C:/evil", body: "x"}}, rule: RuleAbsolutePath, zip: true},
+		{name: "drive letter backslash", entries: []testEntry{{name: `C:\evil`, body: "x"}}, rule: RuleAbsolutePath, zip: true},
+		{name: "symlink outside", entries: []testEntry{{name: "l", link: ".."}}, rule: RuleSymlink, zip: true, absent: []string{"l"}},
+		{name: "symlink absolute", entries: []testEntry{{name: "l", link: "/tmp"}}, rule: RuleSymlink, zip: true, absent: []string{"l"}},
+		{name: "symlink dotdot in target", entries: []testEntry{
+			{name: "d/", dir: true},
+			{name: "l", link: "d/../.."},
+		}, rule: RuleSymlink, zip: true, absent: []string{"l"}},
+		{name: "write through symlink", entries: []testEntry{
+			{name: "l", link: ".."},
+			{name: "l/evil", body: "x"},
+		}, rule: RuleSymlink, zip: true},
+		{name: "symlink chain", entries: []testEntry{
+			{name: "p", link: "."},
+			{name: "m", link: "p/.."},
+			{name: "m/evil", body: "x"},
+		}, rule: RuleSymlink, zip: true, absent: []string{"m"}},
+		{name: "symlink retargeted later", entries: []testEntry{
+			{name: "m", link: "p/.."},
+			{name: "p", link: "."},
+		}, rule: RuleSymlink, zip: true, absent: []string{"m"}},
+		{name: "hardlink to symlink", entries: []testEntry{
+			{name: "f", body: "x"},
+			{name: "d/e/s", link: "../../f"},
+			{name: "h", hard: "d/e/s"},
+		}, rule: RuleSymlink, absent: []string{"h"}},
+		{name: "hardlink outside", entries: []testEntry{{name: "h", hard: "../evil"}}, rule: RuleSymlink, absent: []string{"h"}},
+		{name: "symlink inside", entries: []testEntry{
+			{name: "d/f", body: "x"},
+			{name: "l", link: "d"},
+			{name: "d/e/up", link: "../f"},
+			{name: "h", hard: "d/f"},
+		}},
+	}
+
+	for _, tt := range tests {
+		formats := []string{"tar"}
+		if tt.zip {
+			formats = append(formats, "zip")
+		}
+
+		for _, format := range formats {
+			t.Run(tt.name+"/"+format, func(t *testing.T) {
+				dir := t.TempDir()
+				src := filepath.Join(dir, "a."+format)
+				if format == "zip" {
+					writeZip(t, src, tt.entries)
+				} else {
+					writeTar(t, src, false, tt.entries)
+				}
+
+				out := filepath.Join(dir, "out")
+				if err := os.WriteFile(filepath.Join(dir, "evil"), []byte("keep"), 0644); err != nil {
+					t.Fatal(err)
+				}
+
+				err := FS(src).Extract(out)
+				if rule := unzipRule(err); rule != tt.rule || (tt.rule == "" && err != nil) {
+					t.Fatalf("Extract error = %v, want rule %q", err, tt.rule)
+				}
+
+				if b, _ := os.ReadFile(filepath.Join(dir, "evil")); string(b) != "keep" {
+					t.Fatal("file outside destination was overwritten")
+				}
+				for _, v := range tt.absent {
+					if _, err := os.Lstat(filepath.Join(out, v)); err == nil {
+						t.Errorf("%s should not exist", v)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestExtractTarRatioStreaming(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "bomb.tar.gz")
+	writeTar(t, src, true, []testEntry{{name: "zeros", body: string(make([]byte, 64<<20))}})
+
+	out := filepath.Join(dir, "out")
+	os.MkdirAll(out, 0755)
+	opt := ExtractOptions{UnzipOptions: unzipOptions(UnzipOptions{MaxSize: -1, MaxRatio: 10})}
+	e := &extractor{opt: opt, dst: newJail(out)}
+
+	err := e.tar(src, formatTarGz)
+	if unzipRule(err) != RuleMaxRatio {
+		t.Fatalf("error = %v, want %s", err, RuleMaxRatio)
+	}
+	if e.total > 8<<20 {
+		t.Fatalf("wrote %d bytes before detecting the ratio", e.total)
+	}
+	if FS(out, "zeros").Exist() {
+		t.Fatal("partial entry left on disk")
+	}
+}
@@ -1,10 +1,16 @@
 package snake
 
 import (
+	"bufio"
 	"bytes"
+	"io"
 	"os"
+	"strings"
 )
 
+// streamBlock 流式读取的默认块大小
+const streamBlock = 64 << 10
+
 type snakefile struct {
 	Input *os.File
 }
@@ -14,7 +20,18 @@ type FileOperate interface {
 	Get() *os.File
 	String() *SnakeString
 	Byte() []byte
-	Close() error // 关闭文件链接
+	Write(p []byte) (int, error) // 写入文件，实现 io.Writer
+	Close() error                // 关闭文件链接
+
+	Read(p []byte) (int, error)                        // 从当前位置读取，实现 io.Reader
+	Seek(offset int64, whence int) (int64, error)      // 设置读取位置，实现 io.Seeker
+	ReadAt(p []byte, off int64) (int, error)           // 从off读取，不改变读取位置，实现 io.ReaderAt
+	Lines(fn func(line string) bool) error             // 从当前位置逐行读取，fn返回false时停止
+	Chunks(size int, fn func(chunk []byte) bool) error // 从当前位置按块读取，fn返回false时停止
+	Tail(n int) ([]string, error)                      // 返回最后n行，不改变读取位置
+
+	Text(charset ...string) (string, error)      // 读取剩余内容并转换为 UTF-8，未指定编码时自动检测
+	Decode(charset ...string) (io.Reader, error) // 返回转换为 UTF-8 的 Reader，用于流式读取
 }
 
 // ---------------------------------------
@@ -38,6 +55,11 @@ func (sk *snakefile) Close() error {
 	return sk.Input.Close()
 }
 
+// Write 写入文件...
+func (sk *snakefile) Write(p []byte) (int, error) {
+	return sk.Input.Write(p)
+}
+
 // Text 获取文本...
 func (sk *snakefile) String() *SnakeString {
 	var buf bytes.Buffer
@@ -59,3 +81,198 @@ func (sk *snakefile) Byte() []byte {
 	}
 	return buf.Bytes()
 }
+
+// Text 读取剩余内容并从charset转换为 UTF-8
+// 有 BOM 时根据 BOM 识别 UTF-8、UTF-16LE 与 UTF-16BE 并去除 BOM，
+// 未指定charset时根据开头的内容检测 GBK、Big5、Shift-JIS 等编码。
+// 例子：
+// f, _ := snake.FS("legacy.txt").Open()
+// text, err := f.Text("GB18030")
+func (sk *snakefile) Text(charset ...string) (string, error) {
+	return readText(sk.Input, firstCharset(charset))
+}
+
+// Decode 返回将剩余内容从charset转换为 UTF-8 的 Reader，编码识别规则同 Text
+func (sk *snakefile) Decode(charset ...string) (io.Reader, error) {
+	return decodeText(sk.Input, firstCharset(charset))
+}
+
+// ---------------------------------------
+// 流式读取 :
+// String()、Byte() 读取当前位置之后的全部内容，读取后位置在文件末尾，
+// 再次读取前需 Seek(0, io.SeekStart)。大文件使用以下方法，内存占用与文件大小无关。
+
+// Read 从当前位置读取，实现 io.Reader
+func (sk *snakefile) Read(p []byte) (int, error) {
+	return sk.Input.Read(p)
+}
+
+// Seek 设置读写位置，实现 io.Seeker
+func (sk *snakefile) Seek(offset int64, whence int) (int64, error) {
+	return sk.Input.Seek(offset, whence)
+}
+
+// ReadAt 从off读取，不改变读写位置，实现 io.ReaderAt
+func (sk *snakefile) ReadAt(p []byte, off int64) (int, error) {
+	return sk.Input.ReadAt(p, off)
+}
+
+// Lines 从当前位置逐行读取，去除行尾的 \n 与 \r\n，行的长度不受限制
+// fn返回false时停止，读取位置停在下一行的开头。
+// 例子：
+// f, _ := snake.FS("access.log").Open()
+// f.Lines(func(line string) bool { ...; return true })
+func (sk *snakefile) Lines(fn func(line string) bool) error {
+	return streamLines(sk, fn)
+}
+
+// Chunks 从当前位置按size字节的块读取，最后一块可能不足size，size为0时使用64KB
+// 传给fn的切片在下次调用时被复用。
+func (sk *snakefile) Chunks(size int, fn func(chunk []byte) bool) error {
+	return readChunks(sk.Input, size, fn)
+}
+
+// Tail 返回最后n行，从文件末尾向前按块读取，不改变读写位置
+// 不能随机读取的文件（如管道）从当前位置顺序读取到末尾。
+func (sk *snakefile) Tail(n int) ([]string, error) {
+	info, err := sk.Input.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return tailStream(sk.Input, n)
+	}
+	return tailLines(sk.Input, info.Size(), n)
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// firstCharset 返回可选参数中的编码
+func firstCharset(charset []string) string {
+	if len(charset) > 0 {
+		return charset[0]
+	}
+	return ""
+}
+
+// streamLines 逐行读取r，提前停止时将读取位置退回到未处理的内容之前
+// r不能 Seek 时已缓冲的内容丢失。
+func streamLines(r io.ReadSeeker, fn func(line string) bool) error {
+	br := bufio.NewReaderSize(r, streamBlock)
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 && !fn(trimEOL(line)) {
+			if n := br.Buffered(); n > 0 {
+				r.Seek(-int64(n), io.SeekCurrent)
+			}
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// readChunks 按size字节的块读取r
+func readChunks(r io.Reader, size int, fn func(chunk []byte) bool) error {
+	if size <= 0 {
+		size = streamBlock
+	}
+
+	buf := make([]byte, size)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 && !fn(buf[:n]) {
+			return nil
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// tailLines 从末尾向前按块读取，直到包含n行
+func tailLines(r io.ReaderAt, size int64, n int) ([]string, error) {
+	if n <= 0 || size == 0 {
+		return nil, nil
+	}
+
+	var buf []byte
+	for end := size; end > 0; {
+		start := max(0, end-streamBlock)
+		block := make([]byte, end-start)
+		if _, err := r.ReadAt(block, start); err != nil && err != io.EOF {
+			return nil, err
+		}
+		buf = append(block, buf...)
+		end = start
+
+		// 末尾的换行不计入行数
+		if bytes.Count(bytes.TrimSuffix(buf, []byte("\n")), []byte("\n")) >= n {
+			break
+		}
+	}
+	return lastLines(string(buf), n), nil
+}
+
+// tailStream 顺序读取r，保留最后n行
+func tailStream(r io.Reader, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	// 环形缓冲，count为已读取的行数
+	ring := make([]string, n)
+	count := 0
+
+	br := bufio.NewReaderSize(r, streamBlock)
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			ring[count%n] = trimEOL(line)
+			count++
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if count == 0 {
+		return nil, nil
+	} else if count < n {
+		return ring[:count], nil
+	}
+	return append(ring[count%n:], ring[:count%n]...), nil
+}
+
+// lastLines 返回文本的最后n行
+func lastLines(text string, n int) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+
+	lines := strings.Split(text, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	for i, v := range lines {
+		lines[i] = strings.TrimSuffix(v, "\r")
+	}
+	return lines
+}
+
+// trimEOL 去除行尾的 \n 与 \r\n
+func trimEOL(line string) string {
+	if strings.HasSuffix(line, "\n") {
+		line = strings.TrimSuffix(line[:len(line)-1], "\r")
+	}
+	return line
+}
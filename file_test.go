@@ -0,0 +1,184 @@
+package snake
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// streamText 流式读取测试使用的内容，包含 \r\n、超过缓冲区的长行与不以换行结尾的最后一行
+var streamText = "first\r\nsecond\n" + strings.Repeat("x", 3*streamBlock) + "\n\nlast"
+
+// streamFiles 返回以不同方式打开的同一内容
+func streamFiles(t *testing.T, body string) map[string]func() FileOperate {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	os.WriteFile(path, []byte(body), 0644)
+
+	entries := []testEntry{{name: "a.txt", body: body}}
+	writeZip(t, filepath.Join(dir, "a.zip"), entries)
+	writeTar(t, filepath.Join(dir, "a.tar.gz"), true, entries)
+
+	open := func(fsys FileSystem) FileOperate {
+		f, ok := fsys.Open()
+		if !ok {
+			t.Fatal("Open failed")
+		}
+		t.Cleanup(func() { f.Close() })
+		return f
+	}
+	archive := func(name string) func() FileOperate {
+		return func() FileOperate {
+			ar, err := OpenArchive(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { ar.Close() })
+			return open(ar.FS("a.txt"))
+		}
+	}
+
+	return map[string]func() FileOperate{
+		"file":   func() FileOperate { return open(FS(path)) },
+		"zip":    archive("a.zip"),
+		"tar.gz": archive("a.tar.gz"),
+	}
+}
+
+func TestFileLines(t *testing.T) {
+	want := []string{"first", "second", strings.Repeat("x", 3*streamBlock), "", "last"}
+
+	for name, open := range streamFiles(t, streamText) {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			err := open().Lines(func(line string) bool {
+				got = append(got, line)
+				return true
+			})
+			if err != nil || !reflect.DeepEqual(got, want) {
+				t.Fatalf("Lines = %d lines, %v, want %d", len(got), err, len(want))
+			}
+
+			// 提前停止时读取位置停在下一行的开头
+			f := open()
+			got = nil
+			f.Lines(func(line string) bool {
+				got = append(got, line)
+				return len(got) < 2
+			})
+			if rest := string(f.Byte()); rest != streamText[len("first\r\nsecond\n"):] {
+				t.Fatalf("rest after stop = %d bytes", len(rest))
+			}
+		})
+	}
+}
+
+func TestFileChunks(t *testing.T) {
+	for name, open := range streamFiles(t, "0123456789") {
+		t.Run(name, func(t *testing.T) {
+			tests := []struct {
+				size int
+				stop int
+				want []string
+			}{
+				{4, 0, []string{"0123", "4567", "89"}},
+				{5, 0, []string{"01234", "56789"}},
+				{0, 0, []string{"0123456789"}},
+				{3, 2, []string{"012", "345"}},
+			}
+
+			for _, tt := range tests {
+				var got []string
+				err := open().Chunks(tt.size, func(chunk []byte) bool {
+					got = append(got, string(chunk))
+					return len(got) != tt.stop
+				})
+				if err != nil || !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Chunks(%d) = %q, %v, want %q", tt.size, got, err, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileSeek(t *testing.T) {
+	for name, open := range streamFiles(t, "0123456789") {
+		t.Run(name, func(t *testing.T) {
+			f := open()
+			if pos, err := f.Seek(3, io.SeekStart); err != nil || pos != 3 {
+				t.Fatalf("Seek = %d, %v", pos, err)
+			}
+			p := make([]byte, 2)
+			if n, err := io.ReadFull(f, p); err != nil || string(p[:n]) != "34" {
+				t.Fatalf("Read = %q, %v", p[:n], err)
+			}
+
+			// ReadAt 不改变读取位置
+			if n, err := f.ReadAt(p, 8); string(p[:n]) != "89" || (err != nil && err != io.EOF) {
+				t.Fatalf("ReadAt = %q, %v", p[:n], err)
+			}
+			if n, err := f.ReadAt(p, 9); n != 1 || err != io.EOF {
+				t.Fatalf("ReadAt at end = %d, %v, want 1, EOF", n, err)
+			}
+			if rest := string(f.Byte()); rest != "56789" {
+				t.Fatalf("rest = %q", rest)
+			}
+
+			if pos, err := f.Seek(-2, io.SeekEnd); err != nil || pos != 8 {
+				t.Fatalf("Seek from end = %d, %v", pos, err)
+			}
+			if rest := string(f.Byte()); rest != "89" {
+				t.Fatalf("rest = %q", rest)
+			}
+		})
+	}
+}
+
+func TestFileTail(t *testing.T) {
+	// 跨越多个读取块的内容
+	var b strings.Builder
+	for i := 0; b.Len() < 3*streamBlock; i++ {
+		b.WriteString("line " + strconv.Itoa(i) + "\r\n")
+	}
+	long := b.String()
+	lines := strings.Split(strings.TrimSuffix(long, "\r\n"), "\r\n")
+
+	tests := []struct {
+		body string
+		n    int
+		want []string
+	}{
+		{"a\nb\nc\n", 2, []string{"b", "c"}},
+		{"a\nb\nc", 2, []string{"b", "c"}},
+		{"a\nb\n", 5, []string{"a", "b"}},
+		{"a\n\n", 1, []string{""}},
+		{"a\nb\n", 0, nil},
+		{"", 3, nil},
+		{long, 3, lines[len(lines)-3:]},
+		{long, len(lines) + 1, lines},
+	}
+
+	for _, tt := range tests {
+		for name, open := range streamFiles(t, tt.body) {
+			f := open()
+			got, err := f.Tail(tt.n)
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: Tail(%d) of %d bytes = %d lines, %v, want %d", name, tt.n, len(tt.body), len(got), err, len(tt.want))
+			}
+			// 不改变读取位置
+			if rest := string(f.Byte()); rest != tt.body {
+				t.Errorf("%s: position moved by Tail", name)
+			}
+		}
+
+		// 不能随机读取的文件顺序读取
+		if got, err := tailStream(strings.NewReader(tt.body), tt.n); err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tailStream(%d) of %d bytes = %d lines, %v, want %d", tt.n, len(tt.body), len(got), err, len(tt.want))
+		}
+	}
+}
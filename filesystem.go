@@ -1,7 +1,7 @@
 package snake
 
 import (
-	"archive/zip"
+	"context"
 	"crypto/md5"
 	"crypto/sha256"
 	"encoding/hex"
@@ -14,39 +14,56 @@ import (
 
 // FileSystem ...
 type FileSystem interface {
-	Add(str ...string) FileSystem                     // 新增路径
-	ReplaceRoot(str ...string) FileSystem             //替换根目录位置
-	Dir() string                                      // 返回目录路径
-	Base() string                                     // 返回路径中最后一个元素
-	IsDir(dst ...string) bool                         // 判断是否为目录
-	IsFile(dst ...string) bool                        // 判断是否为文件
-	Ls(opt ...string) []string                        // 查看文件夹列表
-	Find(opt ...string) []string                      // 查找文件
-	MkDir(dst ...string) bool                         // 新建文件夹
-	MkFile(dst ...string) (FileOperate, bool)         // 新建文件
-	Write(src string, add ...bool) bool               // 写入文件
-	ByteWriter(src []byte, add ...bool) (bool, error) // 通过Byte数组写入文件
-	Open(add ...bool) (FileOperate, bool)             // 打开文件
-	Exist(dst ...string) bool                         // 判断目录或文件是否存在
-	Rm(dst ...string) bool                            // 删除目录或文件
-	Rn(newname string) bool                           // 修改目录或文件名
-	Mv(newpath string) bool                           // 移动目录或文件到指定位置
-	Cp(dir string, overwrite bool) bool               // 拷贝目录或文件到指定位置
+	Add(str ...string) FileSystem                                // 新增路径
+	ReplaceRoot(str ...string) FileSystem                        //替换根目录位置
+	Dir() string                                                 // 返回目录路径
+	Base() string                                                // 返回路径中最后一个元素
+	IsDir(dst ...string) bool                                    // 判断是否为目录
+	IsFile(dst ...string) bool                                   // 判断是否为文件
+	Ls(opt ...string) []string                                   // 查看文件夹列表
+	Find(opt ...string) []string                                 // 查找文件
+	MkDir(dst ...string) bool                                    // 新建文件夹
+	MkFile(dst ...string) (FileOperate, bool)                    // 新建文件
+	Write(src string, add ...bool) bool                          // 写入文件
+	ByteWriter(src []byte, add ...bool) (bool, error)            // 通过Byte数组写入文件
+	WriteCharset(src, charset string, add ...bool) (bool, error) // 转换为charset编码后写入文件
+	Open(add ...bool) (FileOperate, bool)                        // 打开文件
+	Exist(dst ...string) bool                                    // 判断目录或文件是否存在
+	Rm(dst ...string) bool                                       // 删除目录或文件
+	Rn(newname string) bool                                      // 修改目录或文件名
+	Mv(newpath string) bool                                      // 移动目录或文件到指定位置
+	Cp(dir string, overwrite bool) bool                          // 拷贝目录或文件到指定位置
 	// SameFile()                      // 文件对比
 	// Chmod()                         // 设置权限
 	// Chown()                         // 设置用户、用户组
 
 	Ext() string // 返回文件扩展名
 	MimeTypes() string
-	MD5() string                   // 返回文件MD5
-	SHA256() string                // 返回文件SHA256
-	Config(conf interface{}) error // 加载配置文件
-	Get() string                   // 返回路径
-	Unzip() (string, error)
+	MD5() string                                                      // 返回文件MD5
+	SHA256() string                                                   // 返回文件SHA256
+	Config(conf interface{}) error                                    // 加载配置文件
+	Get() string                                                      // 返回路径
+	Err() error                                                       // 返回路径错误，如越出 Jail 根目录
+	Unzip(opts ...UnzipOptions) (string, error)                       // 解压zip文件到同名目录
+	Extract(dst string, opts ...ExtractOptions) error                 // 解压归档文件到指定目录
+	Archive(target string, opts ...ArchiveOptions) error              // 将目录归档为zip或tar文件
+	List(opts ...ArchiveReaderOptions) ([]ArchiveEntryInfo, error)    // 列出归档条目信息
+	TestArchive(opts ...ArchiveReaderOptions) ([]EntryCheck, error)   // 校验归档条目的完整性
+	Manifest(algo HashAlgo, target ...string) (*Manifest, error)      // 生成目录的校验清单
+	VerifyManifest(manifest string) (*ManifestReport, error)          // 根据校验清单校验目录
+	Snapshot(repo string, opts ...SnapshotOptions) (*Snapshot, error) // 在快照仓库中为目录创建快照
+	Follow(ctx context.Context, opts ...FollowOptions) *Follower      // 持续读取文件追加的行
+	Transcode(opts ...TranscodeOptions) (*TranscodeReport, error)     // 将目录下的文本文件转换为目标编码
+	ConvertNames(opts ...NameOptions) (*NameReport, error)            // 将目录下非 UTF-8 的文件名转换为 UTF-8
+	Normalize(opts ...NormalizeOptions) (*NormalizeReport, error)     // 规范化换行符、BOM、末尾换行与行尾空白
 }
 
 type snakeFileSystem struct {
 	Path string
+	tx   *Transaction // 所属事务，为空时直接操作磁盘
+	plan *Plan        // 所属计划，不为空时只记录操作不修改磁盘
+	root string       // Jail 根目录，不为空时所有路径限制在该目录内
+	err  error        // 路径错误，不为空时所有操作均失败
 }
 
 // ---------------------------------------
@@ -62,7 +79,12 @@ func FS(str ...string) FileSystem {
 func (sk *snakeFileSystem) Add(str ...string) FileSystem {
 	if len(str) > 0 {
 		for _, v := range str {
-			sk.Path = filepath.Clean(filepath.Join(sk.Path, String(v).Replace(`\`, "/", true).Get()))
+			path := filepath.Clean(filepath.Join(sk.Path, String(v).Replace(`\`, "/", true).Get()))
+			if _, err := sk.resolve(path); err != nil {
+				sk.err = err
+				return sk
+			}
+			sk.Path = path
 		}
 	}
 	return sk
@@ -75,14 +97,36 @@ func (sk *snakeFileSystem) Add(str ...string) FileSystem {
 func (sk *snakeFileSystem) ReplaceRoot(str ...string) FileSystem {
 	path := String(sk.Path).Split("/")
 	path[0] = str[0]
-	return FS(path...)
+	return (&snakeFileSystem{Path: sk.root, tx: sk.tx, plan: sk.plan, root: sk.root, err: sk.err}).Add(path...)
 }
 
-// Cp 拷贝目录或文件
+// Cp 拷贝目录或文件到dir目录下，目标为 dir/Base()，overwrite为false时目标已存在则返回false
+// 拷贝目录时先删除已存在的目标，再复制整个目录。
+// 注意：早期版本拷贝目录时把目录中的内容直接合并到dir中，现在与拷贝文件一致，目录被复制为 dir/Base()。
 func (sk *snakeFileSystem) Cp(dir string, overwrite bool) bool {
-	dstroot := FS(dir)
+	src, ok := sk.pathdst()
+	if !ok {
+		return false
+	}
+
+	dir, ok = sk.pathdst(dir)
+	if !ok {
+		return false
+	}
+
 	dst := FS(dir).Add(sk.Base())
 
+	if _, err := sk.resolve(dst.Get()); err != nil {
+		sk.err = err
+		return false
+	}
+
+	// 源目录中的符号链接不能指向根目录外
+	if err := sk.resolveTree(src); err != nil {
+		sk.err = err
+		return false
+	}
+
 	// todo:目标存在则返回错误
 
 	if dst.Exist() && !overwrite {
@@ -94,34 +138,37 @@ func (sk *snakeFileSystem) Cp(dir string, overwrite bool) bool {
 		return false
 	}
 
-	if sk.IsFile() {
-		return _owcpfile(sk, dst)
-	} else if sk.IsDir() {
-		// 覆盖拷贝目录
-		if dst.Exist() {
-			dst.Rm()
-		}
+	if sk.plan != nil {
+		return sk.plan.cp(sk.Get(), dst.Get(), overwrite)
+	}
 
-		for _, v := range sk.Find("*") {
-			src := FS(v)
-			if src.IsFile() {
-				_owcpfile(src, FS(dstroot.Get(), String(src.Get()).ReplaceOne(sk.Get(), "").Get()))
-			} else if sk.IsDir() {
-				FS(dstroot.Get(), src.Get()).MkDir()
-			}
-		}
+	if sk.tx != nil {
+		return sk.tx.cp(sk, dst.Get())
 	}
 
-	return true
+	return _cp(sk, dst)
 }
 
 // Rm 删除目录及文件
 func (sk *snakeFileSystem) Rm(dst ...string) bool {
-	return os.RemoveAll(sk.pathdst(dst...)) == nil
+	path, ok := sk.pathdst(dst...)
+	if !ok {
+		return false
+	}
+	if sk.plan != nil {
+		return sk.plan.rm(path)
+	}
+	if sk.tx != nil {
+		return sk.tx.rm(path)
+	}
+	return os.RemoveAll(path) == nil
 }
 
 // Open 打开文件
 func (sk *snakeFileSystem) Open(add ...bool) (FileOperate, bool) {
+	if _, ok := sk.pathdst(); !ok {
+		return File(nil), false
+	}
 	if len(add) > 0 && add[0] {
 		file, err := os.OpenFile(sk.Path, os.O_APPEND|os.O_WRONLY, os.ModeAppend)
 		return File(file), err == nil
@@ -132,20 +179,44 @@ func (sk *snakeFileSystem) Open(add ...bool) (FileOperate, bool) {
 
 // Rn 修改目录或文件名
 func (sk *snakeFileSystem) Rn(newname string) bool {
-	res := os.Rename(sk.Path, filepath.Join(sk.Dir(), newname)) == nil
-	if res {
-		sk.Path = filepath.Join(sk.Dir(), newname)
+	newpath := filepath.Join(sk.Dir(), newname)
+	if sk.rename(newpath) {
+		sk.Path = newpath
+		return true
 	}
-	return res
+	return false
 }
 
 // Mv 移动目录或文件到指定位置
 func (sk *snakeFileSystem) Mv(newpath string) bool {
-	res := os.Rename(sk.Path, filepath.Join(newpath, sk.Base())) == nil
-	if res {
-		sk.Path = filepath.Join(newpath, sk.Base())
+	newpath, ok := sk.pathdst(newpath)
+	if !ok {
+		return false
+	}
+	newpath = filepath.Join(newpath, sk.Base())
+	if sk.rename(newpath) {
+		sk.Path = newpath
+		return true
+	}
+	return false
+}
+
+// rename 重命名当前路径，事务模式下记录日志
+func (sk *snakeFileSystem) rename(newpath string) bool {
+	if _, ok := sk.pathdst(); !ok {
+		return false
 	}
-	return res
+	if _, err := sk.resolve(newpath); err != nil {
+		sk.err = err
+		return false
+	}
+	if sk.plan != nil {
+		return sk.plan.rename(sk.Path, newpath)
+	}
+	if sk.tx != nil {
+		return sk.tx.rename(sk.Path, newpath)
+	}
+	return os.Rename(sk.Path, newpath) == nil
 }
 
 // Ext 扩展名
@@ -182,12 +253,23 @@ func (sk *snakeFileSystem) SHA256() string {
 
 // MkDir 创建目录
 func (sk *snakeFileSystem) MkDir(dst ...string) bool {
-	return os.MkdirAll(sk.pathdst(dst...), os.ModePerm) == nil
+	path, ok := sk.pathdst(dst...)
+	if !ok {
+		return false
+	}
+	if sk.tx != nil {
+		return sk.tx.mkdir(path)
+	}
+	return os.MkdirAll(path, os.ModePerm) == nil
 }
 
 // MkFile 创建文件
 func (sk *snakeFileSystem) MkFile(dst ...string) (FileOperate, bool) {
-	p := FS(sk.pathdst(dst...))
+	path, ok := sk.pathdst(dst...)
+	if !ok {
+		return File(nil), false
+	}
+	p := FS(path)
 	if !FS(p.Dir()).Exist() {
 		sk.MkDir(p.Dir())
 	}
@@ -205,6 +287,16 @@ func (sk *snakeFileSystem) Write(src string, add ...bool) bool {
 
 // WriteByte 通过byte数组写入文件, Add为是否追加写入，默认为覆盖写入
 func (sk *snakeFileSystem) ByteWriter(src []byte, add ...bool) (bool, error) {
+	if _, ok := sk.pathdst(); !ok {
+		return false, sk.err
+	}
+	if sk.plan != nil {
+		return sk.plan.write(sk.Path, src, add...)
+	}
+	if sk.tx != nil {
+		return sk.tx.write(sk.Path, src, add...)
+	}
+
 	var f *os.File
 	var err error
 
@@ -235,7 +327,11 @@ func (sk *snakeFileSystem) ByteWriter(src []byte, add ...bool) (bool, error) {
 
 // Exist 判断文件或目录是否存在
 func (sk *snakeFileSystem) Exist(dst ...string) bool {
-	if _, err := os.Stat(sk.pathdst(dst...)); err != nil {
+	path, ok := sk.pathdst(dst...)
+	if !ok {
+		return false
+	}
+	if _, err := os.Stat(path); err != nil {
 		return os.IsExist(err)
 	}
 	return true
@@ -251,6 +347,9 @@ func (sk *snakeFileSystem) Exist(dst ...string) bool {
 // snake.FS("./").LS("*.go")
 // 返回：./路径下的扩展名为.go的所有文件或目录
 func (sk *snakeFileSystem) Ls(opt ...string) []string {
+	if _, ok := sk.pathdst(); !ok {
+		return nil
+	}
 	if len(opt) == 0 {
 		return ls(sk.Path, "*")
 	}
@@ -260,6 +359,9 @@ func (sk *snakeFileSystem) Ls(opt ...string) []string {
 // Find 根据条件搜索路径目录下内容
 // 功能与Ls()方法一直，区别在于Find可以对当前路径下所有目录遍历搜索并返回列表。
 func (sk *snakeFileSystem) Find(opt ...string) []string {
+	if _, ok := sk.pathdst(); !ok {
+		return nil
+	}
 	if len(opt) == 0 {
 		return walkPath(sk.Path, "*")
 	}
@@ -276,28 +378,47 @@ func (sk *snakeFileSystem) Base() string {
 	return filepath.Base(sk.Path)
 }
 
-// IsDir 判断是否是目录
+// IsDir 判断是否是目录，路径不存在或无法访问时返回false
 func (sk *snakeFileSystem) IsDir(dst ...string) bool {
-	if i, err := os.Stat(sk.pathdst(dst...)); !os.IsExist(err) {
+	path, ok := sk.pathdst(dst...)
+	if !ok {
+		return false
+	}
+	if i, err := os.Stat(path); err == nil {
 		return i.Mode().IsDir()
 	}
 	return false
 }
 
-// IsFile 判断是否是目录
+// IsFile 判断是否是普通文件，路径不存在或无法访问时返回false
 func (sk *snakeFileSystem) IsFile(dst ...string) bool {
-	if i, err := os.Stat(sk.pathdst(dst...)); !os.IsExist(err) {
+	path, ok := sk.pathdst(dst...)
+	if !ok {
+		return false
+	}
+	if i, err := os.Stat(path); err == nil {
 		return i.Mode().IsRegular()
 	}
 	return false
 }
 
 // pathdst 处理方法中dst数组，当dst数组为空时，输出Path值，不为空时，输出dst数组的第一个元素。
-func (sk *snakeFileSystem) pathdst(dst ...string) string {
+// Jail 模式下路径越出根目录时返回false。
+func (sk *snakeFileSystem) pathdst(dst ...string) (string, bool) {
+	if sk.err != nil {
+		return "", false
+	}
+
+	path := sk.Path
 	if len(dst) > 0 {
-		return dst[0]
+		path = sk.jailed(dst[0])
 	}
-	return sk.Path
+
+	if _, err := sk.resolve(path); err != nil {
+		sk.err = err
+		return "", false
+	}
+	return path, true
 }
 
 // Get 获取文本...
@@ -305,61 +426,15 @@ func (sk *snakeFileSystem) Get() string {
 	return filepath.Clean(sk.Path)
 }
 
-// Config 加载配置文件...
-func (sk *snakeFileSystem) Config(conf interface{}) error {
-	return configor.Load(conf, sk.Path)
+// Err 返回路径错误...
+func (sk *snakeFileSystem) Err() error {
+	return sk.err
 }
 
-func (sk *snakeFileSystem) Unzip() (string, error) {
-
-	base := FS(sk.Dir()).Add(String(sk.Base()).Remove(sk.Ext()).Get())
-
-	z, err := zip.OpenReader(sk.Path)
-
-	if err != nil {
-		return base.Get(), err
-	}
-
-	defer z.Close()
-
-	base.MkDir()
-
-	for _, file := range z.File {
-
-		item := FS(base.Get()).Add(file.Name)
-
-		// 如果是目录，则创建目录
-		if file.FileInfo().IsDir() && item.MkDir() {
-			continue
-		}
-
-		// 获取到 Reader
-		f, err := file.Open()
-
-		if err != nil {
-			return base.Get(), err
-		}
-
-		defer f.Close()
-
-		if !FS(item.Dir()).Exist() {
-			sk.MkDir(item.Dir())
-		}
-
-		out, err := os.OpenFile(item.Get(), os.O_CREATE|os.O_RDWR|os.O_TRUNC, file.Mode())
-
-		if err != nil {
-			return base.Get(), err
-
-		}
-		_, err = io.Copy(out, f)
-
-		if err != nil {
-			return base.Get(), err
-		}
-
-		defer out.Close()
+// Config 加载配置文件...
+func (sk *snakeFileSystem) Config(conf interface{}) error {
+	if _, ok := sk.pathdst(); !ok {
+		return sk.err
 	}
-
-	return base.Get(), nil
+	return configor.Load(conf, sk.Path)
 }
@@ -0,0 +1,305 @@
+package snake
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// followInterval 默认的检查间隔
+const followInterval = 200 * time.Millisecond
+
+// Follower 持续读取文件追加的行，类似 tail -F
+// Lines 在 ctx 结束或出错时关闭，之后可以通过 Err() 获取错误。
+type Follower struct {
+	Lines <-chan string
+	err   error
+	done  chan struct{}
+}
+
+// FollowOptions 跟踪文件的选项
+type FollowOptions struct {
+	Lines     int           // 先输出文件的最后N行，0时只输出之后追加的行
+	FromStart bool          // 从文件开头输出全部内容，优先于 Lines
+	Interval  time.Duration // 检查追加与轮转的间隔，0时为200ms
+}
+
+// followState 跟踪中的文件
+type followState struct {
+	path    string
+	opt     FollowOptions
+	lines   chan<- string
+	file    *os.File
+	info    os.FileInfo // 打开时文件的信息，用于判断是否被替换
+	pos     int64       // 已读取的字节数
+	pending []byte      // 尚未读到换行的内容
+}
+
+// ---------------------------------------
+// 处理 :
+
+// Follow 持续读取文件追加的行，直到ctx结束
+// 文件被重命名后重新创建（inode 变化）或被截断（大小小于已读取的位置）时，重新打开并从头读取；
+// 文件不存在时等待其创建。行尾的 \n 与 \r\n 被去除，未读到换行的内容等到换行写入后输出。
+// 例子：
+// f := snake.FS("/var/log/app.log").Follow(ctx, snake.FollowOptions{Lines: 10})
+// for line := range f.Lines { ... }
+// if err := f.Err(); err != nil { ... }
+func (sk *snakeFileSystem) Follow(ctx context.Context, opts ...FollowOptions) *Follower {
+	path, ok := sk.pathdst()
+	if !ok {
+		return stoppedFollower(sk.err)
+	}
+
+	var opt FollowOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Interval <= 0 {
+		opt.Interval = followInterval
+	}
+
+	lines := make(chan string, 64)
+	f := &Follower{Lines: lines, done: make(chan struct{})}
+	s := &followState{path: path, opt: opt, lines: lines}
+
+	go func() {
+		err := s.run(ctx)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			err = nil
+		}
+		f.err = err
+		close(f.done)
+		close(lines)
+	}()
+	return f
+}
+
+// Err 返回停止跟踪的错误，ctx结束时为nil，在 Lines 关闭前调用时阻塞
+func (f *Follower) Err() error {
+	<-f.done
+	return f.err
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// stoppedFollower 返回已停止的 Follower
+func stoppedFollower(err error) *Follower {
+	lines := make(chan string)
+	close(lines)
+	f := &Follower{Lines: lines, err: err, done: make(chan struct{})}
+	close(f.done)
+	return f
+}
+
+// run 读取到文件末尾后检查轮转，然后等待追加
+func (s *followState) run(ctx context.Context) error {
+	defer s.close()
+
+	first := true
+	buf := make([]byte, streamBlock)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if s.file == nil {
+			ok, err := s.open(ctx, first)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				// 开始时不存在的文件创建后从头读取
+				first = false
+				if err := s.wait(ctx); err != nil {
+					return err
+				}
+				continue
+			}
+			first = false
+		}
+
+		n, err := s.file.Read(buf)
+		if n > 0 {
+			if err := s.emit(ctx, buf[:n]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if err := s.rotated(ctx); err != nil {
+			return err
+		}
+		if s.file != nil {
+			if err := s.wait(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// open 打开文件，文件不存在时返回false
+// 第一次打开时根据选项从开头、最后N行或末尾开始，之后重新打开时从开头开始。
+func (s *followState) open(ctx context.Context, first bool) (bool, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return false, err
+	}
+	s.file, s.info, s.pos, s.pending = f, info, 0, nil
+
+	if !first || s.opt.FromStart {
+		return true, nil
+	}
+
+	// 最后N行与之后追加的内容以打开时的大小为界，不会遗漏或重复
+	size := info.Size()
+	if s.opt.Lines > 0 {
+		// 末尾未换行的内容不是完整的行，留待换行写入后输出
+		partial := size > 0
+		if partial {
+			last := make([]byte, 1)
+			if _, err := f.ReadAt(last, size-1); err != nil {
+				return false, err
+			}
+			partial = last[0] != '\n'
+		}
+
+		n := s.opt.Lines
+		if partial {
+			n++
+		}
+		lines, err := tailLines(f, size, n)
+		if err != nil {
+			return false, err
+		}
+		if partial && len(lines) > 0 {
+			s.pending = []byte(lines[len(lines)-1])
+			lines = lines[:len(lines)-1]
+		}
+		for _, v := range lines {
+			if err := s.send(ctx, v); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		return false, err
+	}
+	s.pos = size
+	return true, nil
+}
+
+// emit 输出读取内容中的完整行，其余内容留待下次读取
+func (s *followState) emit(ctx context.Context, data []byte) error {
+	s.pos += int64(len(data))
+	data = append(s.pending, data...)
+
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		if err := s.send(ctx, trimEOL(string(data[:i+1]))); err != nil {
+			return err
+		}
+		data = data[i+1:]
+	}
+	s.pending = append([]byte(nil), data...)
+	return nil
+}
+
+// rotated 读到末尾后检查文件是否被替换或截断
+func (s *followState) rotated(ctx context.Context) error {
+	info, err := os.Stat(s.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// 文件被重命名后尚未重新创建，继续读取原文件
+		return nil
+	case err != nil:
+		return err
+	case !os.SameFile(info, s.info):
+		// 文件被替换，读完原文件并输出未换行的内容后打开新文件
+		if err := s.drain(ctx); err != nil {
+			return err
+		}
+		if len(s.pending) > 0 {
+			if err := s.send(ctx, string(s.pending)); err != nil {
+				return err
+			}
+		}
+		s.close()
+	case info.Size() < s.pos:
+		// 文件被截断，从头读取
+		if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		s.pos, s.pending = 0, nil
+	}
+	return nil
+}
+
+// drain 读取原文件到末尾，上次读到末尾后、重命名前追加的内容不会遗漏
+func (s *followState) drain(ctx context.Context) error {
+	buf := make([]byte, streamBlock)
+	for {
+		n, err := s.file.Read(buf)
+		if n > 0 {
+			if err := s.emit(ctx, buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// send 输出一行，ctx结束时返回错误
+func (s *followState) send(ctx context.Context, line string) error {
+	select {
+	case s.lines <- line:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// wait 等待一个检查间隔，ctx结束时返回错误
+func (s *followState) wait(ctx context.Context) error {
+	t := time.NewTimer(s.opt.Interval)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close 关闭当前文件
+func (s *followState) close() {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+}
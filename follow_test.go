@@ -0,0 +1,170 @@
+package snake
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// nextLines 从 Follower 读取n行，超时时测试失败
+func nextLines(t *testing.T, f *Follower, n int) []string {
+	t.Helper()
+	var res []string
+	timeout := time.After(5 * time.Second)
+	for len(res) < n {
+		select {
+		case line, ok := <-f.Lines:
+			if !ok {
+				t.Fatalf("Lines closed after %q: %v", res, f.Err())
+			}
+			res = append(res, line)
+		case <-timeout:
+			t.Fatalf("timed out after %q", res)
+		}
+	}
+	return res
+}
+
+// appendFile 向文件追加内容
+func appendFile(t *testing.T, path, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(s)
+	f.Close()
+}
+
+func TestFollow(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial string // 开始跟踪前的内容，为空时文件不存在
+		opt     FollowOptions
+		change  func(t *testing.T, path string)
+		want    []string
+	}{
+		{
+			name:    "append",
+			initial: "old\n",
+			change:  func(t *testing.T, path string) { appendFile(t, path, "a\r\nb\n") },
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "partial line",
+			initial: "old\n",
+			change: func(t *testing.T, path string) {
+				appendFile(t, path, "par")
+				time.Sleep(20 * time.Millisecond)
+				appendFile(t, path, "tial\n")
+			},
+			want: []string{"partial"},
+		},
+		{
+			name:    "from start",
+			initial: "a\nb\n",
+			opt:     FollowOptions{FromStart: true},
+			change:  func(t *testing.T, path string) { appendFile(t, path, "c\n") },
+			want:    []string{"a", "b", "c"},
+		},
+		{
+			name:    "last lines",
+			initial: "a\nb\nc\npar",
+			opt:     FollowOptions{Lines: 2},
+			change:  func(t *testing.T, path string) { appendFile(t, path, "tial\n") },
+			want:    []string{"b", "c", "partial"},
+		},
+		{
+			name:   "created later",
+			change: func(t *testing.T, path string) { appendFile(t, path, "a\n") },
+			want:   []string{"a"},
+		},
+		{
+			name:    "rotated",
+			initial: "old\n",
+			change: func(t *testing.T, path string) {
+				appendFile(t, path, "a\nunterminated")
+				time.Sleep(20 * time.Millisecond)
+				os.Rename(path, path+".1")
+				appendFile(t, path, "b\n")
+			},
+			want: []string{"a", "unterminated", "b"},
+		},
+		{
+			name:    "truncated",
+			initial: "old line\n",
+			change: func(t *testing.T, path string) {
+				os.WriteFile(path, []byte("a\n"), 0644)
+			},
+			want: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			if tt.initial != "" {
+				os.WriteFile(path, []byte(tt.initial), 0644)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			tt.opt.Interval = 5 * time.Millisecond
+			f := FS(path).Follow(ctx, tt.opt)
+
+			// 等待 Follower 打开文件并读到末尾
+			time.Sleep(20 * time.Millisecond)
+			tt.change(t, path)
+
+			got := nextLines(t, f, len(tt.want))
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("lines = %q, want %q", got, tt.want)
+				}
+			}
+
+			cancel()
+			for range f.Lines {
+			}
+			if err := f.Err(); err != nil {
+				t.Fatalf("Err = %v, want nil after cancel", err)
+			}
+		})
+	}
+}
+
+// TestFollowRotateDrain 读到末尾后、检查轮转前追加到原文件的内容不会遗漏
+func TestFollowRotateDrain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("old\n"), 0644)
+
+	lines := make(chan string, 8)
+	s := &followState{path: path, lines: lines}
+	ctx := context.Background()
+	if ok, err := s.open(ctx, true); !ok || err != nil {
+		t.Fatalf("open = %v, %v", ok, err)
+	}
+	defer s.close()
+
+	appendFile(t, path, "a\nunterminated")
+	os.Rename(path, path+".1")
+	appendFile(t, path, "b\n")
+
+	if err := s.rotated(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s.file != nil {
+		t.Fatal("rotated file still open")
+	}
+	close(lines)
+
+	var got []string
+	for v := range lines {
+		got = append(got, v)
+	}
+	if len(got) != 2 || got[0] != "a" || got[1] != "unterminated" {
+		t.Fatalf("lines = %q, want [a unterminated]", got)
+	}
+}
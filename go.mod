@@ -1,14 +1,19 @@
 module github.com/mycalf/snake
 
-go 1.16
+go 1.22
 
 require (
-	github.com/dsnet/compress v0.0.1 // indirect
-	github.com/gogs/chardet v0.0.0-20191104214054-4b6791f73a28 // indirect
+	github.com/dsnet/compress v0.0.1
 	github.com/jinzhu/configor v1.2.1
+	github.com/klauspost/compress v1.18.0
+	github.com/ulikunitz/xz v0.5.12
 	github.com/yuin/charsetutil v1.0.0
-	golang.org/x/net v0.0.0-20210716203947-853a461950ff // indirect
 	golang.org/x/text v0.3.6
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
 
+require (
+	github.com/BurntSushi/toml v0.3.1 // indirect
+	github.com/gogs/chardet v0.0.0-20191104214054-4b6791f73a28 // indirect
+	golang.org/x/net v0.0.0-20210716203947-853a461950ff // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
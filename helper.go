@@ -72,6 +72,36 @@ func _owcpfile(src FileSystem, dst FileSystem) bool {
 	return false
 }
 
+// _cp 拷贝目录或文件到dst，dst已存在时覆盖，目录被复制为dst本身而不是合并到dst的上级目录
+func _cp(src FileSystem, dst FileSystem) bool {
+	if src.IsFile() {
+		return _owcpfile(src, dst)
+	} else if src.IsDir() {
+		// 覆盖拷贝目录
+		if dst.Exist() {
+			dst.Rm()
+		}
+		dst.MkDir()
+
+		for _, v := range src.Find("*") {
+			rel, err := filepath.Rel(src.Get(), v)
+			if err != nil {
+				return false
+			}
+			item := FS(dst.Get(), rel)
+			if FS(v).IsFile() {
+				if !_owcpfile(FS(v), item) {
+					return false
+				}
+			} else if FS(v).IsDir() {
+				item.MkDir()
+			}
+		}
+		return true
+	}
+	return false
+}
+
 func getEncoding(charset string) encoding.Encoding {
 	if e, err := ianaindex.MIB.Encoding(charset); err == nil && e != nil {
 		return e
@@ -0,0 +1,217 @@
+package snake
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// IgnoreRules gitignore 语法的排除规则
+// 支持 #注释、!取反、结尾 / 只匹配目录、含 / 的规则相对根目录、* ? [] 与 **。
+// 后出现的规则优先；目录被排除时其下所有路径均被排除，不能通过取反重新包含。
+type IgnoreRules struct {
+	rules []ignoreRule
+}
+
+// SkippedEntry 被排除的条目
+type SkippedEntry struct {
+	Path string // 条目路径
+	Rule string // 匹配的规则，来自文件的规则带有 文件名:行号 前缀
+}
+
+// ignoreRule 单条规则
+type ignoreRule struct {
+	text    string // 原始规则
+	source  string // 规则来源，文件名:行号
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+// DefaultIgnore 默认排除规则预设，Tarlib 与 Ziplib 默认不排除任何条目，需显式启用：
+// snake.Tar("a.tar.gz", snake.TarOptions{Ignore: snake.Ignore(snake.DefaultIgnore...)})
+var DefaultIgnore = []string{".DS_Store", "__MACOSX/", ".gitignore", ".index"}
+
+// IgnoreFile 目录中的排除规则文件名，Archive 时自动加载
+const IgnoreFile = ".snakeignore"
+
+// ---------------------------------------
+// 输入 :
+
+// Ignore 根据规则创建排除规则集
+// 例子：
+// snake.Ignore("*.log", "node_modules/", "!keep.log")
+func Ignore(patterns ...string) *IgnoreRules {
+	return (&IgnoreRules{}).Add(patterns...)
+}
+
+// LoadIgnore 从 .snakeignore 等 gitignore 语法的文件加载排除规则
+func LoadIgnore(file string) (*IgnoreRules, error) {
+	r := &IgnoreRules{}
+	if err := r.Load(file); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ---------------------------------------
+// 处理 :
+
+// Add 追加规则，无效的规则被忽略
+func (r *IgnoreRules) Add(patterns ...string) *IgnoreRules {
+	for _, v := range patterns {
+		if rule, ok := parseIgnore(v); ok {
+			r.rules = append(r.rules, rule)
+		}
+	}
+	return r
+}
+
+// Load 从文件追加规则
+func (r *IgnoreRules) Load(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if rule, ok := parseIgnore(scanner.Text()); ok {
+			rule.source = fmt.Sprintf("%s:%d", file, line)
+			r.rules = append(r.rules, rule)
+		}
+	}
+	return scanner.Err()
+}
+
+// Match 判断路径是否被排除，返回匹配的规则
+// name 为相对根目录的路径，isDir 表示是否为目录。
+func (r *IgnoreRules) Match(name string, isDir bool) (string, bool) {
+	if r == nil || len(r.rules) == 0 {
+		return "", false
+	}
+
+	name = strings.Trim(path.Clean("/"+strings.ReplaceAll(name, `\`, "/")), "/")
+	if name == "" {
+		return "", false
+	}
+
+	// 上级目录被排除时其下路径均被排除
+	parts := strings.Split(name, "/")
+	for i := 1; i < len(parts); i++ {
+		if rule, ok := r.match(strings.Join(parts[:i], "/"), true); ok {
+			return rule, true
+		}
+	}
+	return r.match(name, isDir)
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// match 按规则顺序匹配单个路径，最后匹配的规则生效
+func (r *IgnoreRules) match(name string, isDir bool) (string, bool) {
+	var matched *ignoreRule
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(name) {
+			matched = rule
+		}
+	}
+
+	if matched == nil || matched.negate {
+		return "", false
+	}
+	if matched.source != "" {
+		return matched.source + ": " + matched.text, true
+	}
+	return matched.text, true
+}
+
+// parseIgnore 解析一行 gitignore 规则
+func parseIgnore(line string) (ignoreRule, bool) {
+	rule := ignoreRule{text: strings.TrimSpace(line)}
+
+	// 结尾空格被 \ 转义时保留
+	p := strings.TrimRight(line, " \t\r")
+	if strings.HasSuffix(p, `\`) && len(p) < len(strings.TrimRight(line, "\r")) {
+		p += " "
+	}
+
+	if p == "" || strings.HasPrefix(p, "#") {
+		return rule, false
+	}
+
+	if strings.HasPrefix(p, "!") {
+		rule.negate = true
+		p = p[1:]
+	}
+
+	if strings.HasSuffix(p, "/") {
+		rule.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+
+	// 含 / 的规则相对根目录，否则匹配任意层级的名称
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return rule, false
+	}
+
+	expr := globRegexp(p)
+	if !anchored {
+		expr = "(.*/)?" + expr
+	}
+
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// globRegexp 将 gitignore 通配符转换为正则表达式
+func globRegexp(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; {
+		case strings.HasPrefix(p[i:], "**/") && (i == 0 || p[i-1] == '/'):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**") && i+2 == len(p) && (i == 0 || p[i-1] == '/'):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(p):
+			i++
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(p[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := p[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	return b.String()
+}
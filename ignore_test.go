@@ -0,0 +1,115 @@
+package snake
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatch(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		name     string
+		isDir    bool
+		want     bool
+	}{
+		// 不含 / 的规则匹配任意层级
+		{[]string{"*.log"}, "app.log", false, true},
+		{[]string{"*.log"}, "var/log/app.log", false, true},
+		{[]string{"*.log"}, "app.log.txt", false, false},
+		{[]string{"?.txt"}, "a.txt", false, true},
+		{[]string{"?.txt"}, "ab.txt", false, false},
+		{[]string{"[ab].txt"}, "b.txt", false, true},
+		{[]string{"[!ab].txt"}, "b.txt", false, false},
+		{[]string{"[!ab].txt"}, "c.txt", false, true},
+		// 含 / 的规则相对根目录
+		{[]string{"/build"}, "build", true, true},
+		{[]string{"/build"}, "src/build", true, false},
+		{[]string{"docs/*.md"}, "docs/a.md", false, true},
+		{[]string{"docs/*.md"}, "docs/sub/a.md", false, false},
+		{[]string{"docs/*.md"}, "x/docs/a.md", false, false},
+		// **
+		{[]string{"**/cache"}, "a/b/cache", true, true},
+		{[]string{"**/cache"}, "cache", true, true},
+		{[]string{"docs/**"}, "docs/a/b.md", false, true},
+		{[]string{"a/**/z"}, "a/z", false, true},
+		{[]string{"a/**/z"}, "a/b/c/z", false, true},
+		// 结尾 / 只匹配目录，目录被排除时其下路径均被排除
+		{[]string{"node_modules/"}, "node_modules", false, false},
+		{[]string{"node_modules/"}, "node_modules", true, true},
+		{[]string{"node_modules/"}, "web/node_modules/x/index.js", false, true},
+		// 取反与顺序
+		{[]string{"*.log", "!keep.log"}, "keep.log", false, false},
+		{[]string{"!keep.log", "*.log"}, "keep.log", false, true},
+		{[]string{"logs/", "!logs/keep.log"}, "logs/keep.log", false, true},
+		// 注释、空行与转义
+		{[]string{"# comment"}, "# comment", false, false},
+		{[]string{""}, "a", false, false},
+		{[]string{`\#file`}, "#file", false, true},
+		{[]string{`\!important`}, "!important", false, true},
+		{[]string{`trailing\ `}, "trailing ", false, true},
+		// 路径清理
+		{[]string{"*.log"}, `dir\app.log`, false, true},
+		{[]string{"/a.txt"}, "./a.txt", false, true},
+	}
+
+	for _, tt := range tests {
+		_, got := Ignore(tt.patterns...).Match(tt.name, tt.isDir)
+		if got != tt.want {
+			t.Errorf("%q.Match(%q, %v) = %v, want %v", tt.patterns, tt.name, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreNil(t *testing.T) {
+	var r *IgnoreRules
+	if _, ok := r.Match("a.txt", false); ok {
+		t.Fatal("nil rules matched")
+	}
+}
+
+func TestIgnoreDefault(t *testing.T) {
+	r := Ignore(DefaultIgnore...)
+	tests := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{".DS_Store", false, true},
+		{"sub/.DS_Store", false, true},
+		{"__MACOSX", true, true},
+		{"__MACOSX/a/._b", false, true},
+		{".gitignore", false, true},
+		{"index.html", false, false},
+	}
+
+	for _, tt := range tests {
+		if _, got := r.Match(tt.name, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadIgnore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), IgnoreFile)
+	os.WriteFile(file, []byte("# build output\n\ndist/\r\n*.tmp\n!keep.tmp\n"), 0644)
+
+	r, err := LoadIgnore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rule, ok := r.Match("dist/app.js", false); !ok || rule != file+":3: dist/" {
+		t.Fatalf("rule = %q, %v", rule, ok)
+	}
+	if rule, ok := r.Match("a.tmp", false); !ok || rule != file+":4: *.tmp" {
+		t.Fatalf("rule = %q, %v", rule, ok)
+	}
+	if _, ok := r.Match("keep.tmp", false); ok {
+		t.Fatal("negated rule ignored")
+	}
+
+	if _, err := LoadIgnore(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("missing file accepted")
+	}
+}
@@ -0,0 +1,233 @@
+package snake
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ArchiveEntryInfo 归档条目信息
+type ArchiveEntryInfo struct {
+	Name       string      // 条目名称
+	Size       int64       // 解压后的大小
+	Compressed int64       // 压缩后的大小，tar 整体压缩，为-1
+	Mode       os.FileMode // 权限及类型
+	ModTime    time.Time   // 修改时间
+	Method     string      // zip 为条目压缩方式 store、deflate 等，tar 为整体压缩方式 none、gzip 等
+	Encrypted  bool        // 是否加密
+	Link       string      // tar 符号链接或硬链接的目标
+}
+
+// EntryCheck 条目校验结果
+type EntryCheck struct {
+	Name string // 条目名称
+	Size int64  // 实际读取的字节数
+	Err  error  // 校验错误，通过时为nil
+}
+
+// zipMethods zip 压缩方式名称
+var zipMethods = map[uint16]string{
+	zip.Store:   "store",
+	zip.Deflate: "deflate",
+	12:          "bzip2",
+	14:          "lzma",
+	93:          "zstd",
+	95:          "xz",
+}
+
+// ---------------------------------------
+// 处理 :
+
+// List 返回归档中所有条目的信息，不解压内容
+// 格式根据文件头识别，zip 只读取中央目录。
+// 例子：
+// entries, err := snake.FS("upload.zip").List()
+func (sk *snakeFileSystem) List(opts ...ArchiveReaderOptions) ([]ArchiveEntryInfo, error) {
+	src, ok := sk.pathdst()
+	if !ok {
+		return nil, sk.err
+	}
+
+	var opt ArchiveReaderOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	format, err := detectArchive(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if format != formatZip {
+		var res []ArchiveEntryInfo
+		err := walkTar(src, format, func(header *tar.Header, tr *tar.Reader) error {
+			res = append(res, ArchiveEntryInfo{
+				Name:       header.Name,
+				Size:       header.Size,
+				Compressed: -1,
+				Mode:       header.FileInfo().Mode(),
+				ModTime:    header.ModTime,
+				Method:     string(formatCodecs[format]),
+				Link:       header.Linkname,
+			})
+			return nil
+		})
+		return res, err
+	}
+
+	z, err := zip.OpenReader(src)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+
+	if err := decodeZipNames(z.File, opt.Charset); err != nil {
+		return nil, err
+	}
+
+	res := make([]ArchiveEntryInfo, 0, len(z.File))
+	for _, file := range z.File {
+		res = append(res, ArchiveEntryInfo{
+			Name:       file.Name,
+			Size:       int64(file.UncompressedSize64),
+			Compressed: int64(file.CompressedSize64),
+			Mode:       file.Mode(),
+			ModTime:    file.Modified,
+			Method:     zipMethodName(file),
+			Encrypted:  file.Flags&flagEncrypted != 0,
+		})
+	}
+	return res, nil
+}
+
+// TestArchive 读取并校验归档中的所有条目，不写入磁盘
+// zip 校验每个条目的 CRC32 与大小，加密条目需提供密码；
+// tar 校验条目头、内容长度以及 gzip、bzip2、xz、zstd 压缩流的完整性。
+// 返回每个条目的校验结果，任一条目失败或归档结构损坏时同时返回错误。
+// 例子：
+// if _, err := snake.FS("upload.zip").TestArchive(); err != nil { ... }
+func (sk *snakeFileSystem) TestArchive(opts ...ArchiveReaderOptions) ([]EntryCheck, error) {
+	src, ok := sk.pathdst()
+	if !ok {
+		return nil, sk.err
+	}
+
+	var opt ArchiveReaderOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	format, err := detectArchive(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []EntryCheck
+	if format == formatZip {
+		res, err = testZip(src, opt)
+	} else {
+		err = walkTar(src, format, func(header *tar.Header, tr *tar.Reader) error {
+			n, err := io.Copy(io.Discard, tr)
+			res = append(res, EntryCheck{Name: header.Name, Size: n, Err: err})
+			return nil
+		})
+	}
+
+	if err != nil {
+		return res, err
+	}
+
+	for _, v := range res {
+		if v.Err != nil {
+			return res, fmt.Errorf("snake: archive entry %s: %w", v.Name, v.Err)
+		}
+	}
+	return res, nil
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// testZip 读取 zip 中的所有条目，读取结束时由 zip 包校验 CRC32 与大小
+func testZip(src string, opt ArchiveReaderOptions) ([]EntryCheck, error) {
+	z, err := zip.OpenReader(src)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+
+	if err := decodeZipNames(z.File, opt.Charset); err != nil {
+		return nil, err
+	}
+
+	res := make([]EntryCheck, 0, len(z.File))
+	for _, file := range z.File {
+		check := EntryCheck{Name: file.Name}
+
+		r, err := openZipEntry(file, opt.Password)
+		if err == nil {
+			check.Size, err = io.Copy(io.Discard, r)
+			r.Close()
+		}
+		if err == nil && uint64(check.Size) != file.UncompressedSize64 {
+			err = zip.ErrFormat
+		}
+
+		check.Err = err
+		res = append(res, check)
+	}
+	return res, nil
+}
+
+// walkTar 顺序读取 tar 条目，读到结束标记后读完压缩流以校验其完整性
+func walkTar(src string, format archiveFormat, fn func(header *tar.Header, tr *tar.Reader) error) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := decompress(bufio.NewReader(f), format)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if err := fn(header, tr); err != nil {
+			return err
+		}
+	}
+
+	// 压缩流末尾的校验和在 tar 结束标记之后
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// zipMethodName 返回 zip 条目的压缩方式名称，AES 加密条目返回实际的压缩方式
+func zipMethodName(file *zip.File) string {
+	method := file.Method
+	if method == methodAES {
+		if _, _, m, ok := parseAESExtra(file.Extra); ok {
+			method = m
+		}
+	}
+	if name, ok := zipMethods[method]; ok {
+		return name
+	}
+	return fmt.Sprintf("method %d", method)
+}
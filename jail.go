@@ -0,0 +1,158 @@
+package snake
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrJailEscape 路径越出监狱根目录
+var ErrJailEscape = errors.New("snake: path escapes jail root")
+
+// maxSymlinks 解析路径时允许的最大符号链接层数
+const maxSymlinks = 40
+
+// ---------------------------------------
+// 输入 :
+
+// Jail 返回以root为根目录的 FileSystem
+// 所有路径（包括 Add、Cp(dir)、Mv(newpath)、Rn(newname)、Unzip 的解压目标以及 dst 参数）都被限制在root内，
+// 绝对路径视为相对root的路径，通过 .. 或符号链接越出root时返回 ErrJailEscape。
+// 出现越界后该 FileSystem 的所有操作均失败，可通过 Err() 获取错误。
+// 例子：
+// fs := snake.Jail("/srv/uploads").Add(userInput)
+// if fs.Err() != nil { ... }
+func Jail(root string) FileSystem {
+	return newJail(root)
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// newJail 创建以root为根目录的 snakeFileSystem
+func newJail(root string) *snakeFileSystem {
+	sk := &snakeFileSystem{}
+
+	abs, err := filepath.Abs(root)
+	if err == nil {
+		abs, err = realPath(abs)
+	}
+	if err == nil && !FS(abs).IsDir() {
+		err = fmt.Errorf("snake: jail root %s is not a directory", root)
+	}
+
+	sk.Path, sk.root, sk.err = abs, abs, err
+	return sk
+}
+
+// jailed 将dst参数转换为监狱内路径，不在root内的路径视为相对root的路径
+func (sk *snakeFileSystem) jailed(dst string) string {
+	if sk.root == "" {
+		return dst
+	}
+	dst = String(dst).Replace(`\`, "/", true).Get()
+	if filepath.IsAbs(dst) && isWithin(sk.root, filepath.Clean(dst)) {
+		return filepath.Clean(dst)
+	}
+	return filepath.Join(sk.root, dst)
+}
+
+// resolve 校验路径（包括路径中的符号链接）位于监狱根目录内
+func (sk *snakeFileSystem) resolve(path string) (string, error) {
+	if sk.root == "" {
+		return path, nil
+	}
+
+	path = filepath.Clean(path)
+	if !isWithin(sk.root, path) {
+		return path, fmt.Errorf("%w: %s", ErrJailEscape, path)
+	}
+
+	real, err := realPath(path)
+	if err != nil {
+		return path, err
+	}
+	if !isWithin(sk.root, real) {
+		return path, fmt.Errorf("%w: %s -> %s", ErrJailEscape, path, real)
+	}
+
+	return path, nil
+}
+
+// resolveTree 校验目录下所有符号链接均指向监狱根目录内
+func (sk *snakeFileSystem) resolveTree(path string) error {
+	if sk.root == "" {
+		return nil
+	}
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode()&os.ModeSymlink != 0 {
+			_, err = sk.resolve(p)
+		}
+		return err
+	})
+}
+
+// realPath 逐级解析绝对路径中的符号链接（包括悬空链接），返回真实路径
+// 与 filepath.EvalSymlinks 相同，符号链接的目标拆分后放回待解析的部分逐级解析，
+// .. 相对已解析的真实路径处理，不会在解析目标中的符号链接之前按文本消去。
+// 不存在的部分原样拼接，其后的 .. 与符号链接仍逐级处理。
+func realPath(path string) (string, error) {
+	vol := filepath.VolumeName(path)
+	cur := vol + string(filepath.Separator)
+	rest := path[len(vol):]
+
+	links := 0
+	for rest != "" {
+		var part string
+		if i := strings.IndexFunc(rest, isSeparator); i >= 0 {
+			part, rest = rest[:i], rest[i+1:]
+		} else {
+			part, rest = rest, ""
+		}
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			cur = filepath.Dir(cur)
+			continue
+		}
+
+		next := filepath.Join(cur, part)
+		info, err := os.Lstat(next)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			cur = next
+			continue
+		}
+
+		if links++; links > maxSymlinks {
+			return path, fmt.Errorf("snake: too many levels of symbolic links: %s", path)
+		}
+		link, err := os.Readlink(next)
+		if err != nil {
+			return path, err
+		}
+
+		// 目标为绝对路径时从其根目录重新开始，否则相对链接所在的目录
+		if filepath.IsAbs(link) {
+			v := filepath.VolumeName(link)
+			cur, link = v+string(filepath.Separator), link[len(v):]
+		}
+		rest = link + string(filepath.Separator) + rest
+	}
+
+	return cur, nil
+}
+
+// isSeparator 判断是否为路径分隔符
+func isSeparator(r rune) bool {
+	return r < 0x80 && os.IsPathSeparator(uint8(r))
+}
+
+// isWithin 判断path是否位于root内
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
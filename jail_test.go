@@ -0,0 +1,159 @@
+package snake
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newJailDir 创建 <tmp>/root 作为监狱根目录，返回根目录与其上级目录
+func newJailDir(t *testing.T) (string, string) {
+	t.Helper()
+	parent, err := realPath(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(parent, "root")
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return root, parent
+}
+
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlink not supported: %v", err)
+	}
+}
+
+func TestJailTraversal(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   []string
+		want   string // 相对根目录的写入位置，为空时应返回 ErrJailEscape
+		escape bool
+	}{
+		{name: "plain", path: []string{"a", "b.txt"}, want: "a/b.txt"},
+		{name: "dotdot inside", path: []string{"a/../b.txt"}, want: "b.txt"},
+		{name: "dotdot escape", path: []string{"../evil"}, escape: true},
+		{name: "nested escape", path: []string{"a/b/../../../evil"}, escape: true},
+		{name: "escape across args", path: []string{"a", "..", "..", "evil"}, escape: true},
+		{name: "backslash escape", path: []string{`..\evil`}, escape: true},
+		{name: "absolute", path: []string{"/etc/passwd"}, want: "etc/passwd"},
+		{name: "drive letter", path: []string{`C:\evil`}, want: "C:/evil"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, parent := newJailDir(t)
+			fsys := Jail(root).Add(tt.path...)
+			ok := fsys.Write("x")
+
+			if tt.escape {
+				if ok || !errors.Is(fsys.Err(), ErrJailEscape) {
+					t.Fatalf("Write = %v, Err = %v, want ErrJailEscape", ok, fsys.Err())
+				}
+				if FS(parent, "evil").Exist() {
+					t.Fatal("file written outside jail")
+				}
+				return
+			}
+
+			if !ok || fsys.Err() != nil {
+				t.Fatalf("Write = %v, Err = %v", ok, fsys.Err())
+			}
+			if !FS(root, tt.want).IsFile() {
+				t.Fatalf("%s not written inside jail", tt.want)
+			}
+		})
+	}
+}
+
+func TestJailSymlink(t *testing.T) {
+	tests := []struct {
+		name   string
+		links  [][2]string // 按顺序创建的符号链接 {链接, 目标}
+		dirs   []string
+		path   []string
+		escape bool
+	}{
+		{name: "inside", links: [][2]string{{"l", "d"}}, dirs: []string{"d"}, path: []string{"l", "f"}},
+		{name: "outside", links: [][2]string{{"l", ".."}}, path: []string{"l", "evil"}, escape: true},
+		{name: "absolute outside", links: [][2]string{{"l", "$PARENT"}}, path: []string{"l", "evil"}, escape: true},
+		{name: "dotdot in target", links: [][2]string{{"l", "d/../.."}}, dirs: []string{"d"}, path: []string{"l", "evil"}, escape: true},
+		{name: "dotdot in target inside", links: [][2]string{{"l", "d/.."}}, dirs: []string{"d"}, path: []string{"l", "f"}},
+		{name: "chain through self link", links: [][2]string{{"p", "."}, {"m", "p/.."}}, path: []string{"m", "evil"}, escape: true},
+		{name: "chain through nested link", links: [][2]string{{"d/q", "."}, {"m", "d/q/../.."}}, dirs: []string{"d"}, path: []string{"m", "evil"}, escape: true},
+		{name: "dangling outside", links: [][2]string{{"l", "../evil"}}, path: []string{"l"}, escape: true},
+		{name: "loop", links: [][2]string{{"a", "b"}, {"b", "a"}}, path: []string{"a", "f"}, escape: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, parent := newJailDir(t)
+			for _, v := range tt.dirs {
+				os.MkdirAll(filepath.Join(root, v), 0755)
+			}
+			for _, v := range tt.links {
+				target := v[1]
+				if target == "$PARENT" {
+					target = parent
+				}
+				symlink(t, target, filepath.Join(root, v[0]))
+			}
+
+			fsys := Jail(root).Add(tt.path...)
+			ok := fsys.Write("x")
+
+			if !tt.escape {
+				if !ok || fsys.Err() != nil {
+					t.Fatalf("Write = %v, Err = %v", ok, fsys.Err())
+				}
+				return
+			}
+			if ok || fsys.Err() == nil {
+				t.Fatalf("Write = %v, Err = %v, want error", ok, fsys.Err())
+			}
+			if FS(parent, "evil").Exist() {
+				t.Fatal("file written outside jail")
+			}
+		})
+	}
+}
+
+func TestRealPath(t *testing.T) {
+	root, _ := newJailDir(t)
+	os.MkdirAll(filepath.Join(root, "a", "b"), 0755)
+	symlink(t, ".", filepath.Join(root, "p"))
+	symlink(t, "p/..", filepath.Join(root, "m"))
+	symlink(t, "a/b/..", filepath.Join(root, "up"))
+	symlink(t, filepath.Join(root, "a"), filepath.Join(root, "abs"))
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"a/b", "a/b"},
+		{"p/a", "a"},
+		{"m", ".."},
+		{"m/root/a", "a"},
+		{"up/b", "a/b"},
+		{"abs/b/../b", "a/b"},
+		{"missing/../a", "a"},
+		{"missing/x", "missing/x"},
+		{"a/missing/../../p/a", "a"},
+	}
+
+	for _, tt := range tests {
+		got, err := realPath(filepath.Join(root, tt.path))
+		if err != nil {
+			t.Errorf("realPath(%s): %v", tt.path, err)
+			continue
+		}
+		if want := filepath.Join(root, tt.want); got != want {
+			t.Errorf("realPath(%s) = %s, want %s", tt.path, got, want)
+		}
+	}
+}
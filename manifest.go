@@ -0,0 +1,393 @@
+package snake
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// HashAlgo 校验清单使用的摘要算法
+type HashAlgo string
+
+const (
+	HashMD5    HashAlgo = "md5"    // MD5SUMS，与 md5sum 兼容
+	HashSHA1   HashAlgo = "sha1"   // SHA1SUMS，与 sha1sum 兼容
+	HashSHA256 HashAlgo = "sha256" // SHA256SUMS，与 sha256sum 兼容
+	HashSHA512 HashAlgo = "sha512" // SHA512SUMS，与 sha512sum 兼容
+)
+
+// ErrManifestMismatch 目录内容与校验清单不一致
+var ErrManifestMismatch = errors.New("snake: manifest mismatch")
+
+// Manifest 校验清单，每个文件一行，路径相对清单所在的目录
+// String() 输出 sha256sum、md5sum 等命令的格式，可以直接用 sha256sum -c 校验。
+type Manifest struct {
+	Algo    HashAlgo
+	Entries []ManifestEntry // 按路径排序
+}
+
+// ManifestEntry 清单中的一个文件
+type ManifestEntry struct {
+	Path string // 使用 / 分隔的相对路径
+	Sum  string // 十六进制小写摘要
+}
+
+// ManifestReport 校验清单的结果，路径均为相对路径
+type ManifestReport struct {
+	Missing    []string // 清单中有、目录中不存在的文件
+	Extra      []string // 目录中有、清单中没有的文件
+	Mismatched []string // 摘要不一致的文件
+}
+
+// hashSizes 摘要长度与算法的对应关系，用于识别清单的算法
+var hashSizes = map[int]HashAlgo{
+	md5.Size * 2:    HashMD5,
+	sha1.Size * 2:   HashSHA1,
+	sha256.Size * 2: HashSHA256,
+	sha512.Size * 2: HashSHA512,
+}
+
+// ---------------------------------------
+// 输入 :
+
+// ParseManifest 解析 sha256sum、md5sum 等命令输出的清单
+// 支持二进制模式的 * 标记、\ 转义的文件名以及 BSD 格式的 SHA256 (file) = sum，
+// 算法根据摘要长度识别。路径中的 ./ 前缀被去除。
+func ParseManifest(r io.Reader) (*Manifest, error) {
+	m := &Manifest{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		entry, ok := parseManifestLine(text)
+		if !ok {
+			return nil, fmt.Errorf("snake: invalid manifest line %d", line)
+		}
+
+		algo := hashSizes[len(entry.Sum)]
+		if m.Algo == "" {
+			m.Algo = algo
+		} else if algo != m.Algo {
+			return nil, fmt.Errorf("snake: manifest line %d: mixed hash algorithms", line)
+		}
+		m.Entries = append(m.Entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LoadManifest 从文件读取清单
+func LoadManifest(file string) (*Manifest, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseManifest(f)
+}
+
+// ManifestName 返回算法对应的清单文件名，如 SHA256SUMS
+func ManifestName(algo HashAlgo) string {
+	return strings.ToUpper(string(algo)) + "SUMS"
+}
+
+// ---------------------------------------
+// 处理 :
+
+// String 按 sha256sum 的格式输出清单，含 \ 或换行的文件名按 coreutils 的规则转义
+func (m *Manifest) String() string {
+	var b strings.Builder
+	for _, v := range m.Entries {
+		name := v.Path
+		if strings.ContainsAny(name, "\\\n") {
+			b.WriteString(`\`)
+			name = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(name)
+		}
+		b.WriteString(v.Sum + "  " + name + "\n")
+	}
+	return b.String()
+}
+
+// OK 清单与目录内容完全一致
+func (r *ManifestReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Mismatched) == 0
+}
+
+// Manifest 计算目录下所有文件的摘要，生成清单
+// 只包含普通文件，不包含目录与符号链接，路径相对当前目录。
+// 指定target时将清单写入该文件，target位于目录内时不计入清单。
+// 例子：
+// snake.FS("./release").Manifest(snake.HashSHA256, "./release/SHA256SUMS")
+func (sk *snakeFileSystem) Manifest(algo HashAlgo, target ...string) (*Manifest, error) {
+	root, ok := sk.pathdst()
+	if !ok {
+		return nil, sk.err
+	}
+
+	if !sk.IsDir() {
+		return nil, fmt.Errorf("snake: %s is not a directory", root)
+	}
+
+	var file string
+	if len(target) > 0 {
+		if file, ok = sk.pathdst(target[0]); !ok {
+			return nil, sk.err
+		}
+	}
+
+	// 目录中的符号链接不能指向 Jail 根目录外
+	if err := sk.resolveTree(root); err != nil {
+		sk.err = err
+		return nil, err
+	}
+
+	m, err := hashTree(os.DirFS(root), algo, relPath(root, file))
+	if err != nil || file == "" {
+		return m, err
+	}
+
+	w := &snakeFileSystem{Path: file, tx: sk.tx, plan: sk.plan, root: sk.root}
+	if _, err := w.ByteWriter([]byte(m.String())); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// VerifyManifest 根据清单文件校验当前目录，报告缺失、多余与摘要不一致的文件
+// 清单路径相对当前目录，算法根据摘要长度识别；清单位于目录内时不计为多余文件。
+// 不一致时同时返回报告与 ErrManifestMismatch。
+// 例子：
+// report, err := snake.FS("./release").VerifyManifest("./release/SHA256SUMS")
+func (sk *snakeFileSystem) VerifyManifest(manifest string) (*ManifestReport, error) {
+	root, ok := sk.pathdst()
+	if !ok {
+		return nil, sk.err
+	}
+
+	file, ok := sk.pathdst(manifest)
+	if !ok {
+		return nil, sk.err
+	}
+
+	if err := sk.resolveTree(root); err != nil {
+		sk.err = err
+		return nil, err
+	}
+
+	m, err := LoadManifest(file)
+	if err != nil {
+		return nil, err
+	}
+	return verifyTree(os.DirFS(root), m, relPath(root, file))
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// hashTree 计算fsys中所有普通文件的摘要，skip为不计入清单的路径
+func hashTree(fsys fs.FS, algo HashAlgo, skip string) (*Manifest, error) {
+	if _, err := newHash(algo); err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{Algo: algo}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() || p == skip {
+			return err
+		}
+
+		sum, err := hashFile(fsys, p, algo)
+		if err != nil {
+			return err
+		}
+		m.Entries = append(m.Entries, ManifestEntry{Path: p, Sum: sum})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.sorted(), nil
+}
+
+// verifyTree 根据清单校验fsys，skip为不计为多余文件的路径
+func verifyTree(fsys fs.FS, m *Manifest, skip string) (*ManifestReport, error) {
+	report := &ManifestReport{}
+	listed := make(map[string]bool, len(m.Entries))
+
+	for _, v := range m.Entries {
+		listed[v.Path] = true
+
+		if !fs.ValidPath(v.Path) {
+			report.Missing = append(report.Missing, v.Path)
+			continue
+		}
+
+		sum, err := hashFile(fsys, v.Path, m.Algo)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			report.Missing = append(report.Missing, v.Path)
+		case err != nil:
+			return nil, err
+		case !strings.EqualFold(sum, v.Sum):
+			report.Mismatched = append(report.Mismatched, v.Path)
+		}
+	}
+
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() && p != skip && !listed[p] {
+			report.Extra = append(report.Extra, p)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !report.OK() {
+		return report, fmt.Errorf("%w: %d missing, %d extra, %d mismatched",
+			ErrManifestMismatch, len(report.Missing), len(report.Extra), len(report.Mismatched))
+	}
+	return report, nil
+}
+
+// hashFile 计算fsys中单个文件的摘要
+func hashFile(fsys fs.FS, name string, algo HashAlgo) (string, error) {
+	h, err := newHash(algo)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// newHash 根据算法名称创建摘要
+func newHash(algo HashAlgo) (hash.Hash, error) {
+	switch algo {
+	case HashMD5:
+		return md5.New(), nil
+	case HashSHA1:
+		return sha1.New(), nil
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashSHA512:
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("snake: unknown hash algorithm %q", algo)
+}
+
+// parseManifestLine 解析一行清单，支持 GNU 与 BSD 两种格式
+func parseManifestLine(line string) (ManifestEntry, bool) {
+	var entry ManifestEntry
+
+	escaped := strings.HasPrefix(line, `\`)
+	if escaped {
+		line = line[1:]
+	}
+
+	if i := strings.Index(line, " ("); i > 0 && !strings.Contains(line[:i], " ") {
+		// BSD 格式：SHA256 (file) = sum
+		j := strings.LastIndex(line, ") = ")
+		if j < i {
+			return entry, false
+		}
+		entry.Path, entry.Sum = line[i+2:j], line[j+4:]
+	} else {
+		// GNU 格式：sum  file 或 sum *file
+		i := strings.IndexByte(line, ' ')
+		if i < 0 || i+2 > len(line) || (line[i+1] != ' ' && line[i+1] != '*') {
+			return entry, false
+		}
+		entry.Sum, entry.Path = line[:i], line[i+2:]
+	}
+
+	if escaped {
+		entry.Path = unescapeManifest(entry.Path)
+	}
+
+	entry.Sum = strings.ToLower(entry.Sum)
+	if _, ok := hashSizes[len(entry.Sum)]; !ok {
+		return entry, false
+	}
+	if _, err := hex.DecodeString(entry.Sum); err != nil {
+		return entry, false
+	}
+
+	entry.Path = strings.TrimPrefix(path.Clean(filepath.ToSlash(entry.Path)), "./")
+	return entry, entry.Path != "" && entry.Path != "."
+}
+
+// unescapeManifest 还原 coreutils 转义的文件名
+func unescapeManifest(name string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+1 < len(name) {
+			i++
+			if name[i] == 'n' {
+				b.WriteByte('\n')
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// relPath 返回file相对root的 / 分隔路径，file不在root内时返回空
+func relPath(root, file string) string {
+	if file == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(root, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// tee 返回读取时计算摘要的r，读取完成后通过 record 记录条目
+func (m *Manifest) tee(r io.Reader) (io.Reader, hash.Hash) {
+	h, _ := newHash(m.Algo)
+	return io.TeeReader(r, h), h
+}
+
+// record 记录条目的摘要
+func (m *Manifest) record(name string, h hash.Hash) {
+	m.Entries = append(m.Entries, ManifestEntry{Path: name, Sum: hex.EncodeToString(h.Sum(nil))})
+}
+
+// sorted 返回按路径排序的清单
+func (m *Manifest) sorted() *Manifest {
+	sort.SliceStable(m.Entries, func(i, j int) bool { return m.Entries[i].Path < m.Entries[j].Path })
+	return m
+}
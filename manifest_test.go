@@ -0,0 +1,149 @@
+package snake
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sumOf 返回内容的十六进制 SHA-256 摘要
+func sumOf(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestManifest(t *testing.T) {
+	dir := archiveTree(t)
+	target := filepath.Join(dir, "SHA256SUMS")
+
+	m, err := FS(dir).Manifest(HashSHA256, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 只包含普通文件，按路径排序，不包含清单自身
+	want := []ManifestEntry{
+		{"a.txt", sumOf("alpha")},
+		{"run.sh", sumOf("#!/bin/sh\n")},
+		{"sub/b.txt", sumOf("bravo")},
+		{"sub/c.map", sumOf("map")},
+	}
+	if m.Algo != HashSHA256 || !reflect.DeepEqual(m.Entries, want) {
+		t.Fatalf("manifest = %+v, want %+v", m, want)
+	}
+	if b, _ := os.ReadFile(target); string(b) != m.String() {
+		t.Fatalf("written manifest = %q", b)
+	}
+	if !strings.HasPrefix(m.String(), sumOf("alpha")+"  a.txt\n") {
+		t.Fatalf("String = %q", m.String())
+	}
+
+	report, err := FS(dir).VerifyManifest(target)
+	if err != nil || !report.OK() {
+		t.Fatalf("VerifyManifest = %+v, %v", report, err)
+	}
+
+	if _, err := FS(dir).Manifest("crc"); err == nil {
+		t.Fatal("unknown algorithm accepted")
+	}
+	if _, err := FS(target).Manifest(HashSHA256); err == nil {
+		t.Fatal("file accepted as directory")
+	}
+}
+
+func TestVerifyManifestMismatch(t *testing.T) {
+	dir := archiveTree(t)
+	target := filepath.Join(t.TempDir(), "SHA256SUMS")
+	if _, err := FS(dir).Manifest(HashSHA256, target); err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("changed"), 0644)
+	os.Remove(filepath.Join(dir, "sub", "c.map"))
+	os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new"), 0644)
+
+	report, err := FS(dir).VerifyManifest(target)
+	if !errors.Is(err, ErrManifestMismatch) {
+		t.Fatalf("err = %v, want ErrManifestMismatch", err)
+	}
+	want := &ManifestReport{Missing: []string{"sub/c.map"}, Extra: []string{"new.txt"}, Mismatched: []string{"a.txt"}}
+	if report.OK() || !reflect.DeepEqual(report, want) {
+		t.Fatalf("report = %+v, want %+v", report, want)
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	md5sum := md5.Sum([]byte("alpha"))
+	md5hex := hex.EncodeToString(md5sum[:])
+	sha := sumOf("alpha")
+
+	tests := []struct {
+		name  string
+		input string
+		algo  HashAlgo
+		want  []ManifestEntry
+	}{
+		{"gnu", sha + "  a.txt\n" + sha + "  sub/b.txt\n", HashSHA256,
+			[]ManifestEntry{{"a.txt", sha}, {"sub/b.txt", sha}}},
+		{"binary", md5hex + " *a.bin\n", HashMD5, []ManifestEntry{{"a.bin", md5hex}}},
+		{"bsd", "SHA256 (dir/a b.txt) = " + strings.ToUpper(sha) + "\n", HashSHA256,
+			[]ManifestEntry{{"dir/a b.txt", sha}}},
+		{"escaped", `\` + sha + `  a\\b\nc` + "\n", HashSHA256, []ManifestEntry{{"a\\b\nc", sha}}},
+		{"dot prefix and crlf", sha + "  ./a.txt\r\n\r\n", HashSHA256, []ManifestEntry{{"a.txt", sha}}},
+		{"empty", "", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseManifest(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Algo != tt.algo || !reflect.DeepEqual(m.Entries, tt.want) {
+				t.Fatalf("manifest = %+v, want %s %+v", m, tt.algo, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseManifestInvalid(t *testing.T) {
+	sha := sumOf("alpha")
+	tests := []string{
+		"not a manifest\n",
+		sha + " a.txt\n",
+		"abc  a.txt\n",
+		strings.Repeat("zz", 32) + "  a.txt\n",
+		sha + "  .\n",
+		"SHA256 (a.txt = " + sha + "\n",
+		sha + "  a.txt\n" + hex.EncodeToString(make([]byte, md5.Size)) + "  b.txt\n",
+	}
+
+	for _, input := range tests {
+		if m, err := ParseManifest(strings.NewReader(input)); err == nil {
+			t.Errorf("%q parsed as %+v", input, m)
+		}
+	}
+}
+
+// TestManifestEscape 含 \ 或换行的文件名输出后能被解析还原
+func TestManifestEscape(t *testing.T) {
+	m := &Manifest{Algo: HashSHA256, Entries: []ManifestEntry{
+		{"plain.txt", sumOf("a")},
+		{`back\slash`, sumOf("b")},
+		{"new\nline", sumOf("c")},
+	}}
+
+	got, err := ParseManifest(strings.NewReader(m.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Fatalf("round trip = %+v, want %+v", got, m)
+	}
+}
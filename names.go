@@ -0,0 +1,243 @@
+package snake
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NameOptions 转换文件名编码的选项
+type NameOptions struct {
+	From      string // 文件名的源编码，为空时根据目录中所有非 UTF-8 的文件名检测
+	Normalize string // Unicode 规范化形式 NFC 或 NFD，为空时不规范化；macOS 的文件名多为 NFD
+}
+
+// NameReport 转换文件名编码的结果，路径均为相对路径
+type NameReport struct {
+	Renamed    []NameChange // 已重命名的文件与目录
+	Collisions []NameChange // 新名称已存在或与其它文件的新名称相同，保持原名
+	Failed     []NameChange // 不能确定编码或不能重命名，保持原名
+}
+
+// NameChange 单个文件名的转换结果
+type NameChange struct {
+	Path    string // 原路径
+	Target  string // 新路径
+	Charset string // 检测到的源编码，原名称为 UTF-8 时为空
+	Err     error  // 失败原因
+}
+
+// ---------------------------------------
+// 处理 :
+
+// ConvertNames 将目录下非 UTF-8 的文件名（如从 Windows 共享复制的 GBK、Big5 文件名）转换为 UTF-8，
+// 并可按 NFC 或 NFD 规范化所有文件名。先重命名子目录中的文件，再重命名目录本身。
+// 新名称已存在时记入 Collisions，不会覆盖。通过 DryRun 计划调用时只记录将要执行的重命名。
+// 例子：
+// report, err := snake.FS("./share").ConvertNames(snake.NameOptions{Normalize: "NFC"})
+// plan := snake.DryRun()
+// report, err := plan.FS("./share").ConvertNames()
+func (sk *snakeFileSystem) ConvertNames(opts ...NameOptions) (*NameReport, error) {
+	root, ok := sk.pathdst()
+	if !ok {
+		return nil, sk.err
+	}
+
+	if !sk.IsDir() {
+		return nil, fmt.Errorf("snake: %s is not a directory", root)
+	}
+
+	var opt NameOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	var form norm.Form
+	switch strings.ToUpper(opt.Normalize) {
+	case "":
+	case "NFC":
+		form = norm.NFC
+	case "NFD":
+		form = norm.NFD
+	default:
+		return nil, fmt.Errorf("snake: unknown unicode normalization form %s", opt.Normalize)
+	}
+
+	if opt.From != "" {
+		if _, err := textEncoding(opt.From); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := sk.resolveTree(root); err != nil {
+		sk.err = err
+		return nil, err
+	}
+
+	var paths []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 单个文件名太短，根据所有非 UTF-8 的文件名检测编码
+	charset := opt.From
+	if charset == "" {
+		charset = nameCharset(paths)
+	}
+
+	report := &NameReport{}
+	claimed := make(map[string]bool)
+
+	// Walk 按先目录后内容的顺序返回，倒序处理使目录中的内容先于目录重命名
+	for i := len(paths) - 1; i >= 0; i-- {
+		path := paths[i]
+		name := filepath.Base(path)
+
+		from, newname, err := convertName(name, charset, opt.From != "")
+		if err == nil && opt.Normalize != "" {
+			newname = form.String(newname)
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		change := NameChange{Path: filepath.ToSlash(rel), Charset: from}
+		if err != nil {
+			change.Err = err
+			report.Failed = append(report.Failed, change)
+			continue
+		}
+		if newname == name {
+			continue
+		}
+
+		target := filepath.Join(filepath.Dir(path), newname)
+		change.Target = filepath.ToSlash(filepath.Join(filepath.Dir(rel), newname))
+
+		if claimed[target] || nameTaken(path, target) {
+			change.Err = fmt.Errorf("snake: %s already exists", change.Target)
+			report.Collisions = append(report.Collisions, change)
+			continue
+		}
+
+		f := &snakeFileSystem{Path: path, tx: sk.tx, plan: sk.plan, root: sk.root}
+		if !f.rename(target) {
+			change.Err = f.err
+			if change.Err == nil {
+				change.Err = fmt.Errorf("snake: rename %s failed", change.Path)
+			}
+			report.Failed = append(report.Failed, change)
+			continue
+		}
+		claimed[target] = true
+		report.Renamed = append(report.Renamed, change)
+	}
+
+	// 倒序处理后恢复为目录在前的顺序
+	reverseChanges(report.Renamed)
+	reverseChanges(report.Collisions)
+	reverseChanges(report.Failed)
+
+	if n := len(report.Collisions) + len(report.Failed); n > 0 {
+		return report, fmt.Errorf("snake: %d names could not be converted", n)
+	}
+	return report, nil
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// nameCharset 根据所有非 UTF-8 的文件名检测编码，都是 UTF-8 时返回空
+func nameCharset(paths []string) string {
+	var sample []string
+	for _, v := range paths {
+		if name := filepath.Base(v); !utf8.ValidString(name) {
+			sample = append(sample, name)
+		}
+	}
+	if len(sample) == 0 {
+		return ""
+	}
+
+	charset, _ := String(strings.Join(sample, "\n")).Charset()
+	return charset
+}
+
+// convertName 将非 UTF-8 的文件名从charset转换为 UTF-8，返回使用的编码
+// 不能用charset原样编码回去时，fixed为false则单独检测该文件名的编码。
+func convertName(name, charset string, fixed bool) (string, string, error) {
+	if utf8.ValidString(name) {
+		return "", name, nil
+	}
+
+	newname, err := decodeName(name, charset)
+	if err != nil && !fixed {
+		if c, ok := String(name).Charset(); ok && c != charset {
+			charset = c
+			newname, err = decodeName(name, charset)
+		}
+	}
+	if err != nil {
+		return charset, "", err
+	}
+	return charset, newname, nil
+}
+
+// decodeName 将文件名从charset转换为 UTF-8，结果能原样编码回去才认为转换可信
+func decodeName(name, charset string) (string, error) {
+	if charset == "" {
+		return "", errors.New("snake: cannot detect charset")
+	}
+
+	enc, err := textEncoding(charset)
+	if err != nil {
+		return "", err
+	}
+
+	text, err := enc.NewDecoder().String(name)
+	if err == nil {
+		var back string
+		back, err = enc.NewEncoder().String(text)
+		if err == nil && back != name {
+			err = fmt.Errorf("snake: name is not valid %s", charset)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if text == "" || text == "." || text == ".." || strings.ContainsAny(text, "/\x00") {
+		return "", fmt.Errorf("snake: name is not valid %s", charset)
+	}
+	return text, nil
+}
+
+// nameTaken 判断target是否已被其它文件占用
+// 不区分规范化形式的文件系统（如 APFS）中target与path是同一个文件，不算冲突。
+func nameTaken(path, target string) bool {
+	info, err := os.Lstat(target)
+	if err != nil {
+		return false
+	}
+	src, err := os.Lstat(path)
+	return err != nil || !os.SameFile(src, info)
+}
+
+// reverseChanges 倒转结果的顺序
+func reverseChanges(changes []NameChange) {
+	for i, j := 0, len(changes)-1; i < j; i, j = i+1, j-1 {
+		changes[i], changes[j] = changes[j], changes[i]
+	}
+}
@@ -0,0 +1,189 @@
+package snake
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// gbkName 返回名称的 GBK 编码
+func gbkName(t *testing.T, name string) string {
+	t.Helper()
+	b, err := encodeText(name, "GBK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// namesTree 生成文件名为 GBK 编码的目录
+func namesTree(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "share")
+	sub := filepath.Join(dir, gbkName(t, "中文目录"))
+	os.MkdirAll(sub, 0755)
+	os.WriteFile(filepath.Join(sub, gbkName(t, "会议纪要与报告.txt")), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(dir, gbkName(t, "简体中文说明文档.txt")), []byte("b"), 0644)
+	os.WriteFile(filepath.Join(dir, "utf8.txt"), []byte("c"), 0644)
+	return dir
+}
+
+// treeNames 返回目录下的所有相对路径
+func treeNames(t *testing.T, dir string) []string {
+	t.Helper()
+	var res []string
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && path != dir {
+			rel, _ := filepath.Rel(dir, path)
+			res = append(res, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	sort.Strings(res)
+	return res
+}
+
+// changeTargets 返回结果中的新路径
+func changeTargets(changes []NameChange) []string {
+	var res []string
+	for _, v := range changes {
+		res = append(res, v.Target)
+	}
+	return res
+}
+
+func TestConvertNames(t *testing.T) {
+	want := []string{"中文目录", "中文目录/会议纪要与报告.txt", "简体中文说明文档.txt", "utf8.txt"}
+	sort.Strings(want)
+
+	for _, from := range []string{"", "GBK"} {
+		t.Run("from="+from, func(t *testing.T) {
+			dir := namesTree(t)
+			report, err := FS(dir).ConvertNames(NameOptions{From: from})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := treeNames(t, dir); !reflect.DeepEqual(got, want) {
+				t.Fatalf("names = %q, want %q", got, want)
+			}
+
+			// 按原名称的字节顺序，目录在其内容之前，上级路径为转换前的路径
+			targets := []string{"简体中文说明文档.txt", "中文目录", gbkName(t, "中文目录") + "/会议纪要与报告.txt"}
+			if got := changeTargets(report.Renamed); !reflect.DeepEqual(got, targets) {
+				t.Fatalf("Renamed = %q, want %q", got, targets)
+			}
+			if report.Renamed[1].Path != gbkName(t, "中文目录") || report.Renamed[1].Charset == "" {
+				t.Fatalf("Renamed[1] = %+v", report.Renamed[1])
+			}
+		})
+	}
+}
+
+// TestConvertNamesCollision 新名称已存在时保持原名，不覆盖
+func TestConvertNamesCollision(t *testing.T) {
+	dir := namesTree(t)
+	os.WriteFile(filepath.Join(dir, "简体中文说明文档.txt"), []byte("existing"), 0644)
+
+	report, err := FS(dir).ConvertNames(NameOptions{From: "GBK"})
+	if err == nil {
+		t.Fatal("collision not reported")
+	}
+	if got := changeTargets(report.Collisions); !reflect.DeepEqual(got, []string{"简体中文说明文档.txt"}) {
+		t.Fatalf("Collisions = %q", got)
+	}
+	if len(report.Renamed) != 2 {
+		t.Fatalf("Renamed = %+v", report.Renamed)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "简体中文说明文档.txt")); string(b) != "existing" {
+		t.Fatal("existing file overwritten")
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, gbkName(t, "简体中文说明文档.txt"))); string(b) != "b" {
+		t.Fatal("colliding file renamed")
+	}
+}
+
+func TestConvertNamesFailed(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "\xff\xff.txt"), []byte("a"), 0644)
+
+	report, err := FS(dir).ConvertNames(NameOptions{From: "GBK"})
+	if err == nil || len(report.Failed) != 1 || report.Failed[0].Err == nil {
+		t.Fatalf("report = %+v, %v", report, err)
+	}
+	if !FS(dir, "\xff\xff.txt").Exist() {
+		t.Fatal("failed name changed")
+	}
+
+	tests := []NameOptions{{From: "unknown"}, {Normalize: "NFKC"}}
+	for _, opt := range tests {
+		if _, err := FS(dir).ConvertNames(opt); err == nil {
+			t.Errorf("%+v accepted", opt)
+		}
+	}
+}
+
+func TestConvertNamesNormalize(t *testing.T) {
+	nfc, nfd := norm.NFC.String("café.txt"), norm.NFD.String("café.txt")
+
+	tests := []struct {
+		form string
+		name string
+		want string
+	}{
+		{"NFC", nfd, nfc},
+		{"nfd", nfc, nfd},
+		{"NFC", nfc, nfc},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.form, func(t *testing.T) {
+			dir := t.TempDir()
+			os.WriteFile(filepath.Join(dir, tt.name), []byte("a"), 0644)
+
+			report, err := FS(dir).ConvertNames(NameOptions{Normalize: tt.form})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := treeNames(t, dir); !reflect.DeepEqual(got, []string{tt.want}) {
+				t.Fatalf("names = %q, want %q", got, tt.want)
+			}
+			if renamed := len(report.Renamed) == 1; renamed != (tt.name != tt.want) {
+				t.Fatalf("Renamed = %+v", report.Renamed)
+			}
+		})
+	}
+}
+
+// TestConvertNamesPlan DryRun 时只记录重命名，执行计划后与直接转换的结果相同
+func TestConvertNamesPlan(t *testing.T) {
+	dir := namesTree(t)
+	before := treeNames(t, dir)
+
+	plan := DryRun()
+	report, err := plan.FS(dir).ConvertNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != len(report.Renamed) || len(plan.Steps) != 3 {
+		t.Fatalf("recorded %d steps for %d renames", len(plan.Steps), len(report.Renamed))
+	}
+	if !strings.Contains(plan.String(), "中文目录") {
+		t.Fatalf("plan = %s", plan)
+	}
+	if got := treeNames(t, dir); !reflect.DeepEqual(got, before) {
+		t.Fatal("dry run renamed files")
+	}
+
+	if err := plan.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"utf8.txt", "中文目录", "中文目录/会议纪要与报告.txt", "简体中文说明文档.txt"}
+	if got := treeNames(t, dir); !reflect.DeepEqual(got, want) {
+		t.Fatalf("names = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,316 @@
+package snake
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrNotNormalized 检查模式下存在不符合要求的文件
+var ErrNotNormalized = errors.New("snake: files are not normalized")
+
+// LineEnding 换行符类型
+type LineEnding string
+
+const (
+	EOLLF    LineEnding = "LF"    // \n，Unix、macOS
+	EOLCRLF  LineEnding = "CRLF"  // \r\n，Windows
+	EOLCR    LineEnding = "CR"    // \r，早期 Mac OS
+	EOLMixed LineEnding = "mixed" // 混用多种换行符，只用于检测结果
+	EOLNone  LineEnding = "none"  // 没有换行符，只用于检测结果
+)
+
+// BOMAction 对 UTF-8 BOM 的处理方式
+type BOMAction string
+
+const (
+	BOMKeep  BOMAction = ""      // 保持不变
+	BOMAdd   BOMAction = "add"   // 添加 UTF-8 BOM
+	BOMStrip BOMAction = "strip" // 去除 UTF-8 BOM
+)
+
+// NormalizeIssue 文件不符合要求的原因
+type NormalizeIssue string
+
+const (
+	IssueLineEnding         NormalizeIssue = "line ending"           // 换行符与目标不一致
+	IssueBOM                NormalizeIssue = "bom"                   // 缺少或多余 UTF-8 BOM
+	IssueFinalNewline       NormalizeIssue = "missing final newline" // 文件末尾没有换行符
+	IssueTrailingWhitespace NormalizeIssue = "trailing whitespace"   // 行尾有空格或制表符
+)
+
+// NormalizeOptions 规范化文本文件的选项，零值不做任何修改
+type NormalizeOptions struct {
+	EOL          LineEnding // 目标换行符 LF、CRLF 或 CR，为空时保持不变
+	BOM          BOMAction  // UTF-8 BOM 的处理方式
+	FinalNewline bool       // 非空文件末尾没有换行符时添加
+	TrimSpace    bool       // 去除行尾的空格与制表符
+	Include      []string   // 只处理匹配的路径，如 *.go、*.md，为空时处理全部文本文件
+	Exclude      []string   // 不处理匹配的路径，匹配的目录整体跳过
+	Check        bool       // 只检查不修改，有不符合要求的文件时返回 ErrNotNormalized
+}
+
+// NormalizeReport 规范化的结果，路径均为相对路径
+type NormalizeReport struct {
+	Changed []NormalizeFile // 已修改的文件，检查模式下为不符合要求的文件
+	Binary  []string        // 跳过的二进制文件与 UTF-16 文件
+	Failed  []NormalizeFile // 不能处理的文件，如向非 UTF-8 文件添加 BOM
+}
+
+// NormalizeFile 单个文件的规范化结果
+type NormalizeFile struct {
+	Path   string           // 相对路径
+	EOL    LineEnding       // 修改前的换行符
+	Issues []NormalizeIssue // 不符合要求的原因
+	Err    error            // 失败原因
+}
+
+// ---------------------------------------
+// 处理 :
+
+// Normalize 规范化文件或目录下所有文本文件的换行符、UTF-8 BOM、末尾换行与行尾空白
+// 换行符按字节处理，适用于 UTF-8 与 GBK、Big5、Shift-JIS 等兼容 ASCII 的编码；
+// 含 NUL 字节的二进制文件与 UTF-16 文件跳过。修改通过 ByteWriter 写入，支持事务与 DryRun。
+// Check 为true时不修改文件，存在不符合要求的文件时同时返回报告与 ErrNotNormalized，用于 CI 检查。
+// 例子：
+// opt := snake.NormalizeOptions{EOL: snake.EOLLF, BOM: snake.BOMStrip, FinalNewline: true, TrimSpace: true}
+// snake.FS("./src").Normalize(opt)
+// opt.Check = true
+// report, err := snake.FS("./src").Normalize(opt)
+func (sk *snakeFileSystem) Normalize(opts ...NormalizeOptions) (*NormalizeReport, error) {
+	root, ok := sk.pathdst()
+	if !ok {
+		return nil, sk.err
+	}
+
+	var opt NormalizeOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	switch opt.EOL {
+	case "", EOLLF, EOLCRLF, EOLCR:
+	default:
+		return nil, fmt.Errorf("snake: unknown line ending %s", opt.EOL)
+	}
+	switch opt.BOM {
+	case BOMKeep, BOMAdd, BOMStrip:
+	default:
+		return nil, fmt.Errorf("snake: unknown bom action %s", opt.BOM)
+	}
+
+	report := &NormalizeReport{}
+	file := func(path, name string, info os.FileInfo) error {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if isBinary(body) || bytes.HasPrefix(body, bomUTF16LE) || bytes.HasPrefix(body, bomUTF16BE) {
+			report.Binary = append(report.Binary, name)
+			return nil
+		}
+
+		res := NormalizeFile{Path: name, EOL: DetectEOL(body)}
+		out, issues, err := normalizeText(body, opt)
+		if err != nil {
+			res.Err = err
+			report.Failed = append(report.Failed, res)
+			return nil
+		}
+		if len(issues) == 0 {
+			return nil
+		}
+
+		res.Issues = issues
+		report.Changed = append(report.Changed, res)
+		if opt.Check {
+			return nil
+		}
+		return sk.writeAs(path, out, info)
+	}
+
+	// 单个文件时不使用 Include 与 Exclude
+	if sk.IsFile() {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		err = file(root, filepath.Base(root), info)
+		return report, normalizeErr(report, opt, err)
+	}
+
+	if err := sk.resolveTree(root); err != nil {
+		sk.err = err
+		return nil, err
+	}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		if matchPath(opt.Exclude, name) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || (len(opt.Include) > 0 && !matchPath(opt.Include, name)) {
+			return nil
+		}
+		return file(path, name, info)
+	})
+	return report, normalizeErr(report, opt, err)
+}
+
+// DetectEOL 检测内容使用的换行符，混用时返回 EOLMixed，没有换行符时返回 EOLNone
+func DetectEOL(body []byte) LineEnding {
+	var crlf, lf, cr int
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\n':
+			lf++
+		case '\r':
+			if i+1 < len(body) && body[i+1] == '\n' {
+				crlf++
+				i++
+			} else {
+				cr++
+			}
+		}
+	}
+
+	switch {
+	case crlf+lf+cr == 0:
+		return EOLNone
+	case crlf == 0 && cr == 0:
+		return EOLLF
+	case lf == 0 && cr == 0:
+		return EOLCRLF
+	case crlf == 0 && lf == 0:
+		return EOLCR
+	}
+	return EOLMixed
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// normalizeErr 合并遍历的错误与检查、失败的结果
+func normalizeErr(report *NormalizeReport, opt NormalizeOptions, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if opt.Check && len(report.Changed) > 0 {
+		names := make([]string, len(report.Changed))
+		for i, v := range report.Changed {
+			names[i] = v.Path
+		}
+		return fmt.Errorf("%w: %s", ErrNotNormalized, strings.Join(names, ", "))
+	}
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("snake: %d files could not be normalized", len(report.Failed))
+	}
+	return nil
+}
+
+// normalizeText 按选项规范化内容，返回新内容与不符合要求的原因
+func normalizeText(body []byte, opt NormalizeOptions) ([]byte, []NormalizeIssue, error) {
+	var issues []NormalizeIssue
+
+	hasBOM := bytes.HasPrefix(body, bomUTF8)
+	text := bytes.TrimPrefix(body, bomUTF8)
+	switch {
+	case opt.BOM == BOMAdd && !hasBOM:
+		if !utf8.Valid(text) {
+			return nil, nil, errors.New("snake: cannot add UTF-8 BOM to non UTF-8 content")
+		}
+		issues = append(issues, IssueBOM)
+		hasBOM = true
+	case opt.BOM == BOMStrip && hasBOM:
+		issues = append(issues, IssueBOM)
+		hasBOM = false
+	}
+
+	// 末尾补充的换行符：指定目标时使用目标，否则使用文件中的换行符，混用或没有时使用 LF
+	eol := eolBytes(opt.EOL)
+	final := eol
+	if final == nil {
+		if final = eolBytes(DetectEOL(text)); final == nil {
+			final = []byte("\n")
+		}
+	}
+
+	var out bytes.Buffer
+	if hasBOM {
+		out.Write(bomUTF8)
+	}
+
+	var eolIssue, spaceIssue, finalIssue bool
+	for len(text) > 0 {
+		line, sep := text, []byte(nil)
+		if i := bytes.IndexAny(text, "\r\n"); i >= 0 {
+			line, sep = text[:i], text[i:i+1]
+			if text[i] == '\r' && i+1 < len(text) && text[i+1] == '\n' {
+				sep = text[i : i+2]
+			}
+		}
+		text = text[len(line)+len(sep):]
+
+		if opt.TrimSpace {
+			if trimmed := bytes.TrimRight(line, " \t"); len(trimmed) != len(line) {
+				line, spaceIssue = trimmed, true
+			}
+		}
+		out.Write(line)
+
+		switch {
+		case sep == nil:
+			// 最后一行去除空白后为空时，前一行的换行符就是末尾换行
+			if opt.FinalNewline && len(line) > 0 {
+				out.Write(final)
+				finalIssue = true
+			}
+		case eol != nil && !bytes.Equal(sep, eol):
+			out.Write(eol)
+			eolIssue = true
+		default:
+			out.Write(sep)
+		}
+	}
+
+	if eolIssue {
+		issues = append(issues, IssueLineEnding)
+	}
+	if finalIssue {
+		issues = append(issues, IssueFinalNewline)
+	}
+	if spaceIssue {
+		issues = append(issues, IssueTrailingWhitespace)
+	}
+	return out.Bytes(), issues, nil
+}
+
+// eolBytes 返回换行符对应的字节，混用或没有换行符时返回nil
+func eolBytes(eol LineEnding) []byte {
+	switch eol {
+	case EOLLF:
+		return []byte("\n")
+	case EOLCRLF:
+		return []byte("\r\n")
+	case EOLCR:
+		return []byte("\r")
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package snake
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectEOL(t *testing.T) {
+	tests := []struct {
+		body string
+		want LineEnding
+	}{
+		{"a\nb\n", EOLLF},
+		{"a\r\nb\r\n", EOLCRLF},
+		{"a\rb\r", EOLCR},
+		{"a\r\nb\n", EOLMixed},
+		{"a\rb\n", EOLMixed},
+		{"abc", EOLNone},
+		{"", EOLNone},
+		{"a\r", EOLCR},
+	}
+
+	for _, tt := range tests {
+		if got := DetectEOL([]byte(tt.body)); got != tt.want {
+			t.Errorf("DetectEOL(%q) = %s, want %s", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	bom := "\xef\xbb\xbf"
+
+	tests := []struct {
+		name   string
+		body   string
+		opt    NormalizeOptions
+		want   string
+		issues []NormalizeIssue
+	}{
+		{"zero options", "a \r\nb\rc", NormalizeOptions{}, "a \r\nb\rc", nil},
+		{"to lf", "a\r\nb\rc\n", NormalizeOptions{EOL: EOLLF}, "a\nb\nc\n", []NormalizeIssue{IssueLineEnding}},
+		{"to crlf", "a\nb\r\n", NormalizeOptions{EOL: EOLCRLF}, "a\r\nb\r\n", []NormalizeIssue{IssueLineEnding}},
+		{"to cr", "a\nb", NormalizeOptions{EOL: EOLCR}, "a\rb", []NormalizeIssue{IssueLineEnding}},
+		{"already lf", "a\nb\n", NormalizeOptions{EOL: EOLLF, FinalNewline: true}, "a\nb\n", nil},
+		{"strip bom", bom + "a\n", NormalizeOptions{BOM: BOMStrip}, "a\n", []NormalizeIssue{IssueBOM}},
+		{"add bom", "a\n", NormalizeOptions{BOM: BOMAdd}, bom + "a\n", []NormalizeIssue{IssueBOM}},
+		{"keep bom", bom + "a\n", NormalizeOptions{BOM: BOMAdd}, bom + "a\n", nil},
+		// 末尾换行使用目标换行符，未指定时使用文件中的换行符
+		{"final newline", "a\r\nb", NormalizeOptions{FinalNewline: true}, "a\r\nb\r\n", []NormalizeIssue{IssueFinalNewline}},
+		{"final newline mixed", "a\r\nb\nc", NormalizeOptions{FinalNewline: true}, "a\r\nb\nc\n", []NormalizeIssue{IssueFinalNewline}},
+		{"final newline target", "a", NormalizeOptions{EOL: EOLCRLF, FinalNewline: true}, "a\r\n", []NormalizeIssue{IssueFinalNewline}},
+		{"final newline empty", "", NormalizeOptions{FinalNewline: true}, "", nil},
+		{"trim space", "a \t\nb\t\n  \n", NormalizeOptions{TrimSpace: true}, "a\nb\n\n", []NormalizeIssue{IssueTrailingWhitespace}},
+		{"trim last line", "a\n  ", NormalizeOptions{TrimSpace: true, FinalNewline: true}, "a\n", []NormalizeIssue{IssueTrailingWhitespace}},
+		{"all", bom + "a \r\nb", NormalizeOptions{EOL: EOLLF, BOM: BOMStrip, FinalNewline: true, TrimSpace: true}, "a\nb\n",
+			[]NormalizeIssue{IssueBOM, IssueLineEnding, IssueFinalNewline, IssueTrailingWhitespace}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, issues, err := normalizeText([]byte(tt.body), tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tt.want || !reflect.DeepEqual(issues, tt.issues) {
+				t.Fatalf("normalizeText = %q %v, want %q %v", out, issues, tt.want, tt.issues)
+			}
+		})
+	}
+
+	gbk, _ := encodeText("中文\n", "GBK")
+	if _, _, err := normalizeText(gbk, NormalizeOptions{BOM: BOMAdd}); err == nil {
+		t.Fatal("BOM added to GBK content")
+	}
+}
+
+// normalizeTree 生成规范化测试使用的目录
+func normalizeTree(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "src")
+	os.MkdirAll(filepath.Join(dir, "vendor"), 0755)
+	os.WriteFile(filepath.Join(dir, "crlf.txt"), []byte("a\r\nb\r\n"), 0600)
+	os.WriteFile(filepath.Join(dir, "clean.txt"), []byte("a\nb\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "space.go"), []byte("package a \n"), 0644)
+	os.WriteFile(filepath.Join(dir, "image.bin"), []byte("a\r\n\x00"), 0644)
+	os.WriteFile(filepath.Join(dir, "utf16.txt"), []byte("\xff\xfea\x00\r\x00\n\x00"), 0644)
+	os.WriteFile(filepath.Join(dir, "vendor", "lib.txt"), []byte("a\r\n"), 0644)
+	return dir
+}
+
+// normalizePaths 返回结果中的路径
+func normalizePaths(files []NormalizeFile) []string {
+	var res []string
+	for _, v := range files {
+		res = append(res, v.Path)
+	}
+	return res
+}
+
+func TestNormalize(t *testing.T) {
+	dir := normalizeTree(t)
+	opt := NormalizeOptions{EOL: EOLLF, TrimSpace: true, Exclude: []string{"vendor"}}
+
+	report, err := FS(dir).Normalize(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := normalizePaths(report.Changed); !reflect.DeepEqual(got, []string{"crlf.txt", "space.go"}) {
+		t.Fatalf("Changed = %v", got)
+	}
+	if report.Changed[0].EOL != EOLCRLF || !reflect.DeepEqual(report.Changed[0].Issues, []NormalizeIssue{IssueLineEnding}) {
+		t.Fatalf("Changed[0] = %+v", report.Changed[0])
+	}
+	if !reflect.DeepEqual(report.Binary, []string{"image.bin", "utf16.txt"}) {
+		t.Fatalf("Binary = %v", report.Binary)
+	}
+
+	tests := map[string]string{
+		"crlf.txt":       "a\nb\n",
+		"space.go":       "package a\n",
+		"image.bin":      "a\r\n\x00",
+		"vendor/lib.txt": "a\r\n",
+	}
+	for name, want := range tests {
+		if b, _ := os.ReadFile(filepath.Join(dir, name)); string(b) != want {
+			t.Errorf("%s = %q, want %q", name, b, want)
+		}
+	}
+	// 保留原文件的权限
+	if info, _ := os.Stat(filepath.Join(dir, "crlf.txt")); info.Mode().Perm() != 0600 {
+		t.Fatalf("crlf.txt mode = %v", info.Mode())
+	}
+
+	// 只处理 Include 匹配的文件
+	dir = normalizeTree(t)
+	report, err = FS(dir).Normalize(NormalizeOptions{EOL: EOLLF, Include: []string{"*.go"}})
+	if err != nil || !reflect.DeepEqual(normalizePaths(report.Changed), []string(nil)) {
+		t.Fatalf("Changed = %v, %v", normalizePaths(report.Changed), err)
+	}
+	report, err = FS(dir).Normalize(NormalizeOptions{EOL: EOLLF, Include: []string{"vendor/*"}})
+	if err != nil || !reflect.DeepEqual(normalizePaths(report.Changed), []string{"vendor/lib.txt"}) {
+		t.Fatalf("Changed = %v, %v", normalizePaths(report.Changed), err)
+	}
+}
+
+// TestNormalizeCheck 检查模式不修改文件，存在不符合要求的文件时返回 ErrNotNormalized
+func TestNormalizeCheck(t *testing.T) {
+	dir := normalizeTree(t)
+	opt := NormalizeOptions{EOL: EOLLF, FinalNewline: true, TrimSpace: true, Check: true}
+
+	report, err := FS(dir).Normalize(opt)
+	if !errors.Is(err, ErrNotNormalized) {
+		t.Fatalf("err = %v, want ErrNotNormalized", err)
+	}
+	want := []string{"crlf.txt", "space.go", "vendor/lib.txt"}
+	if got := normalizePaths(report.Changed); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Changed = %v, want %v", got, want)
+	}
+	if !strings.Contains(err.Error(), strings.Join(want, ", ")) {
+		t.Fatalf("err = %v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "crlf.txt")); string(b) != "a\r\nb\r\n" {
+		t.Fatal("check mode modified a file")
+	}
+
+	// 修改后再次检查通过
+	opt.Check = false
+	if _, err := FS(dir).Normalize(opt); err != nil {
+		t.Fatal(err)
+	}
+	opt.Check = true
+	if report, err := FS(dir).Normalize(opt); err != nil || len(report.Changed) != 0 {
+		t.Fatalf("second check = %+v, %v", report, err)
+	}
+}
+
+func TestNormalizeFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	os.WriteFile(path, []byte("a\r\n"), 0644)
+
+	// 单个文件时不使用 Include
+	report, err := FS(path).Normalize(NormalizeOptions{EOL: EOLLF, Include: []string{"*.go"}})
+	if err != nil || !reflect.DeepEqual(normalizePaths(report.Changed), []string{"a.txt"}) {
+		t.Fatalf("report = %+v, %v", report, err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "a\n" {
+		t.Fatalf("content = %q", b)
+	}
+
+	gbk := filepath.Join(dir, "gbk.txt")
+	body, _ := encodeText("中文\n", "GBK")
+	os.WriteFile(gbk, body, 0644)
+	report, err = FS(gbk).Normalize(NormalizeOptions{BOM: BOMAdd})
+	if err == nil || len(report.Failed) != 1 || report.Failed[0].Err == nil {
+		t.Fatalf("report = %+v, %v", report, err)
+	}
+
+	tests := []NormalizeOptions{{EOL: "LFCR"}, {BOM: "remove"}}
+	for _, opt := range tests {
+		if _, err := FS(path).Normalize(opt); err == nil {
+			t.Errorf("%+v accepted", opt)
+		}
+	}
+}
+
+// TestNormalizePlan DryRun 时只记录计划，不修改文件
+func TestNormalizePlan(t *testing.T) {
+	dir := normalizeTree(t)
+
+	plan := DryRun()
+	report, err := plan.FS(dir).Normalize(NormalizeOptions{EOL: EOLLF})
+	if err != nil || len(report.Changed) != 2 || len(plan.Steps) != 2 {
+		t.Fatalf("report = %+v, %d steps, %v", report, len(plan.Steps), err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "crlf.txt")); string(b) != "a\r\nb\r\n" {
+		t.Fatal("dry run modified a file")
+	}
+}
@@ -0,0 +1,327 @@
+package snake
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Plan 操作计划（演练模式）
+// 通过 Plan.FS 得到的 FileSystem 调用 Rm、Mv、Rn、Cp、Write、Unzip、Extract 时不会修改磁盘，
+// 而是记录将要执行的操作、涉及的数据大小以及冲突（覆盖、缺少上级目录等），
+// 计划经过审核后可通过 Exec 原样执行。
+// 冲突根据记录时的磁盘状态判断，不考虑计划中前面步骤的影响。
+// 例子：
+// plan := snake.DryRun()
+// plan.FS("./cache").Rm()
+// plan.FS("./logs/app.log").Mv("./archive")
+// fmt.Println(plan)
+// err := plan.Exec()
+type Plan struct {
+	Steps []PlanStep
+}
+
+// PlanStep 计划中的一步操作
+type PlanStep struct {
+	Op        string         // 操作类型：rm、mv、cp、write、unzip、extract
+	Path      string         // 源路径
+	Target    string         // 目标路径
+	Size      int64          // 涉及的数据大小
+	Conflicts []PlanConflict // 冲突列表
+
+	data      []byte           // write: 写入内容
+	add       bool             // write: 是否追加写入
+	overwrite bool             // cp: 是否覆盖
+	unzip     []UnzipOptions   // unzip: 解压选项
+	extract   []ExtractOptions // extract: 解压选项
+}
+
+// PlanConflict 计划冲突类型
+type PlanConflict string
+
+const (
+	ConflictOverwrite     PlanConflict = "overwrite"      // 目标已存在，将被覆盖
+	ConflictMissingParent PlanConflict = "missing parent" // 目标的上级目录不存在
+	ConflictMissingSource PlanConflict = "missing source" // 源路径不存在
+)
+
+const (
+	planOpRm      = "rm"
+	planOpMv      = "mv"
+	planOpCp      = "cp"
+	planOpWrite   = "write"
+	planOpUnzip   = "unzip"
+	planOpExtract = "extract"
+)
+
+// ---------------------------------------
+// 输入 :
+
+// DryRun 新建操作计划
+func DryRun() *Plan {
+	return &Plan{}
+}
+
+// FS 返回记录到当前计划的 FileSystem
+func (p *Plan) FS(str ...string) FileSystem {
+	sk := &snakeFileSystem{plan: p}
+	return sk.Add(str...)
+}
+
+// ---------------------------------------
+// 输出 :
+
+// Size 返回计划涉及的数据总大小
+func (p *Plan) Size() int64 {
+	var size int64
+	for _, v := range p.Steps {
+		size += v.Size
+	}
+	return size
+}
+
+// Conflicts 返回存在冲突的步骤
+func (p *Plan) Conflicts() []PlanStep {
+	var res []PlanStep
+	for _, v := range p.Steps {
+		if len(v.Conflicts) > 0 {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// String 输出可供审核的计划文本
+func (p *Plan) String() string {
+	str := String()
+	for i, v := range p.Steps {
+		str.Add(fmt.Sprintf("%3d  %-5s  %s", i+1, v.Op, v.Path))
+		if v.Target != "" {
+			str.Add(" -> ", v.Target)
+		}
+		str.Add("  (", formatSize(v.Size), ")")
+		for _, c := range v.Conflicts {
+			str.Add("  [", c, "]")
+		}
+		str.Ln()
+	}
+	return str.Add(fmt.Sprintf("%d operations, %s", len(p.Steps), formatSize(p.Size()))).Get()
+}
+
+// Exec 按顺序执行计划，遇到失败的步骤时停止并返回错误
+func (p *Plan) Exec() error {
+	for i, v := range p.Steps {
+		if err := v.exec(); err != nil {
+			return fmt.Errorf("snake: plan step %d (%s %s): %w", i+1, v.Op, v.Path, err)
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------
+// 计划操作 :
+
+// rm 记录删除操作
+func (p *Plan) rm(path string) bool {
+	step := PlanStep{Op: planOpRm, Path: path}
+	if FS(path).Exist() {
+		step.Size = pathSize(path)
+	} else {
+		step.Conflicts = append(step.Conflicts, ConflictMissingSource)
+	}
+	p.Steps = append(p.Steps, step)
+	return true
+}
+
+// rename 记录移动操作
+func (p *Plan) rename(from, to string) bool {
+	step := PlanStep{Op: planOpMv, Path: from, Target: to}
+	if FS(from).Exist() {
+		step.Size = pathSize(from)
+	} else {
+		step.Conflicts = append(step.Conflicts, ConflictMissingSource)
+	}
+	step.Conflicts = append(step.Conflicts, targetConflicts(to)...)
+	p.Steps = append(p.Steps, step)
+	return FS(from).Exist() && FS(filepath.Dir(to)).IsDir()
+}
+
+// cp 记录拷贝操作
+func (p *Plan) cp(src, dst string, overwrite bool) bool {
+	step := PlanStep{Op: planOpCp, Path: src, Target: dst, overwrite: overwrite}
+	if FS(src).Exist() {
+		step.Size = pathSize(src)
+	} else {
+		step.Conflicts = append(step.Conflicts, ConflictMissingSource)
+	}
+	step.Conflicts = append(step.Conflicts, targetConflicts(dst)...)
+	p.Steps = append(p.Steps, step)
+	return FS(src).Exist()
+}
+
+// write 记录写入操作
+func (p *Plan) write(path string, src []byte, add ...bool) (bool, error) {
+	step := PlanStep{Op: planOpWrite, Path: path, Size: int64(len(src)), data: append([]byte(nil), src...)}
+	step.add = len(add) > 0 && add[0]
+	if !step.add || !FS(path).IsFile() {
+		step.Conflicts = targetConflicts(path)
+	}
+	p.Steps = append(p.Steps, step)
+	return true, nil
+}
+
+// unzip 记录解压操作
+// 与 Unzip 相同，先转换条目名称的编码并校验路径、数量与声明的大小，违反规则时返回 *UnzipError。
+func (p *Plan) unzip(path, base string, opts ...UnzipOptions) (string, error) {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return base, err
+	}
+	defer z.Close()
+
+	opt := unzipOptions(opts...)
+	if err := decodeZipNames(z.File, opt.Charset); err != nil {
+		return base, err
+	}
+	if err := opt.check(z.File); err != nil {
+		return base, err
+	}
+
+	step := PlanStep{Op: planOpUnzip, Path: path, Target: base, unzip: opts}
+	overwrite := false
+	for _, file := range z.File {
+		name, _ := entryName(file.Name)
+		step.Size += int64(file.UncompressedSize64)
+		if !file.FileInfo().IsDir() && FS(base, filepath.FromSlash(name)).Exist() {
+			overwrite = true
+		}
+	}
+	if overwrite {
+		step.Conflicts = append(step.Conflicts, ConflictOverwrite)
+	}
+	p.Steps = append(p.Steps, step)
+	return base, nil
+}
+
+// extract 记录解压操作
+// 与 Extract 相同，先校验条目路径、数量与声明的大小，违反规则时返回 *UnzipError；
+// tar 的整体压缩比需要解压全部内容才能确定，在执行时校验。
+func (p *Plan) extract(src, dst string, opts ...ExtractOptions) error {
+	var opt ExtractOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	opt.UnzipOptions = unzipOptions(opt.UnzipOptions)
+
+	entries, err := (&snakeFileSystem{Path: src}).List(ArchiveReaderOptions{Charset: opt.Charset})
+	if err != nil {
+		return err
+	}
+
+	step := PlanStep{Op: planOpExtract, Path: src, Target: dst, extract: opts}
+	overwrite := false
+	var total int64
+	for i, v := range entries {
+		name, err := entryName(v.Name)
+		if err != nil {
+			return err
+		}
+		if opt.MaxFiles > 0 && i >= opt.MaxFiles {
+			return &UnzipError{Entry: v.Name, Rule: RuleMaxFiles}
+		}
+		if total += v.Size; opt.MaxSize > 0 && total > opt.MaxSize {
+			return &UnzipError{Entry: v.Name, Rule: RuleMaxSize}
+		}
+		if v.Compressed >= 0 && opt.exceedRatio(uint64(v.Size), uint64(v.Compressed)) {
+			return &UnzipError{Entry: v.Name, Rule: RuleMaxRatio}
+		}
+
+		if name = opt.strip(name); name == "" || !opt.match(name) {
+			continue
+		}
+		step.Size += v.Size
+		if !v.Mode.IsDir() && FS(dst, filepath.FromSlash(name)).Exist() {
+			overwrite = true
+		}
+	}
+	if overwrite {
+		step.Conflicts = append(step.Conflicts, ConflictOverwrite)
+	}
+	p.Steps = append(p.Steps, step)
+	return nil
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// exec 执行一步操作
+func (s PlanStep) exec() error {
+	ok := false
+	switch s.Op {
+	case planOpRm:
+		ok = FS(s.Path).Rm()
+	case planOpMv:
+		ok = os.Rename(s.Path, s.Target) == nil
+	case planOpCp:
+		ok = FS(s.Path).Cp(filepath.Dir(s.Target), s.overwrite)
+	case planOpWrite:
+		var err error
+		if ok, err = FS(s.Path).ByteWriter(s.data, s.add); err != nil {
+			return err
+		}
+	case planOpUnzip:
+		if _, err := FS(s.Path).Unzip(s.unzip...); err != nil {
+			return err
+		}
+		ok = true
+	case planOpExtract:
+		if err := FS(s.Path).Extract(s.Target, s.extract...); err != nil {
+			return err
+		}
+		ok = true
+	}
+	if !ok {
+		return fmt.Errorf("operation failed")
+	}
+	return nil
+}
+
+// targetConflicts 判断写入目标的冲突
+func targetConflicts(path string) []PlanConflict {
+	var res []PlanConflict
+	if FS(path).Exist() {
+		res = append(res, ConflictOverwrite)
+	}
+	if !FS(filepath.Dir(path)).IsDir() {
+		res = append(res, ConflictMissingParent)
+	}
+	return res
+}
+
+// pathSize 返回文件或目录下所有文件的大小
+func pathSize(path string) int64 {
+	var size int64
+	filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// formatSize 将字节数格式化为易读的大小
+func formatSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	f := float64(size)
+	i := 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.1f %s", f, units[i])
+}
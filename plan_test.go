@@ -0,0 +1,127 @@
+package snake
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanUnzip(t *testing.T) {
+	gbk, _ := encodeText("中文.txt", "GBK")
+
+	tests := []struct {
+		name     string
+		entries  []testEntry
+		opt      UnzipOptions
+		existing string    // 解压目录中已存在的文件
+		rule     UnzipRule // 为空时应记录计划
+		conflict bool
+	}{
+		{name: "plain", entries: []testEntry{{name: "a/b.txt", body: "x"}}},
+		{name: "overwrite", entries: []testEntry{{name: "a/b.txt", body: "x"}}, existing: "a/b.txt", conflict: true},
+		{name: "traversal", entries: []testEntry{{name: "../evil", body: "x"}}, rule: RulePathTraversal},
+		{name: "absolute", entries: []testEntry{{name: "/evil", body: "x"}}, rule: RuleAbsolutePath},
+		{name: "backslash", entries: []testEntry{{name: `a\b.txt`, body: "x"}}, existing: "a/b.txt", conflict: true},
+		{name: "max files", entries: []testEntry{{name: "a", body: "x"}, {name: "b", body: "x"}}, opt: UnzipOptions{MaxFiles: 1}, rule: RuleMaxFiles},
+		{name: "legacy name", entries: []testEntry{{name: string(gbk), body: "x"}}, opt: UnzipOptions{Charset: "GBK"}, existing: "中文.txt", conflict: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "a.zip")
+			writeZip(t, src, tt.entries)
+			if tt.existing != "" {
+				FS(dir, "a", tt.existing).Write("old")
+			}
+
+			plan := DryRun()
+			_, err := plan.FS(src).Unzip(tt.opt)
+			if rule := unzipRule(err); rule != tt.rule || (tt.rule == "" && err != nil) {
+				t.Fatalf("Unzip error = %v, want rule %q", err, tt.rule)
+			}
+			if tt.rule != "" {
+				if len(plan.Steps) != 0 {
+					t.Fatalf("rejected archive recorded %d steps", len(plan.Steps))
+				}
+				return
+			}
+
+			if len(plan.Steps) != 1 {
+				t.Fatalf("recorded %d steps, want 1", len(plan.Steps))
+			}
+			if got := len(plan.Conflicts()) > 0; got != tt.conflict {
+				t.Fatalf("conflict = %v, want %v", got, tt.conflict)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "a")); tt.existing == "" && err == nil {
+				t.Fatal("dry run wrote to disk")
+			}
+		})
+	}
+}
+
+func TestPlanExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  []testEntry
+		opt      ExtractOptions
+		existing string    // 解压目录中已存在的文件
+		rule     UnzipRule // 为空时应记录计划
+		conflict bool
+	}{
+		{name: "plain", entries: []testEntry{{name: "a/b.txt", body: "x"}}},
+		{name: "overwrite", entries: []testEntry{{name: "a/b.txt", body: "x"}}, existing: "a/b.txt", conflict: true},
+		{name: "strip", entries: []testEntry{{name: "top/b.txt", body: "x"}}, opt: ExtractOptions{StripComponents: 1}, existing: "b.txt", conflict: true},
+		{name: "excluded", entries: []testEntry{{name: "b.txt", body: "x"}}, opt: ExtractOptions{Exclude: []string{"*.txt"}}, existing: "b.txt"},
+		{name: "traversal", entries: []testEntry{{name: "../evil", body: "x"}}, rule: RulePathTraversal},
+		{name: "max files", entries: []testEntry{{name: "a", body: "x"}, {name: "b", body: "x"}}, opt: ExtractOptions{UnzipOptions: UnzipOptions{MaxFiles: 1}}, rule: RuleMaxFiles},
+		{name: "max size", entries: []testEntry{{name: "a", body: "xx"}}, opt: ExtractOptions{UnzipOptions: UnzipOptions{MaxSize: 1}}, rule: RuleMaxSize},
+	}
+
+	for _, tt := range tests {
+		for _, format := range []string{"zip", "tar"} {
+			t.Run(tt.name+"/"+format, func(t *testing.T) {
+				dir := t.TempDir()
+				src := filepath.Join(dir, "a."+format)
+				if format == "zip" {
+					writeZip(t, src, tt.entries)
+				} else {
+					writeTar(t, src, false, tt.entries)
+				}
+				out := filepath.Join(dir, "out")
+				if tt.existing != "" {
+					FS(out, tt.existing).Write("old")
+				}
+
+				plan := DryRun()
+				err := plan.FS(src).Extract(out, tt.opt)
+				if rule := unzipRule(err); rule != tt.rule || (tt.rule == "" && err != nil) {
+					t.Fatalf("Extract error = %v, want rule %q", err, tt.rule)
+				}
+				if tt.rule != "" {
+					if len(plan.Steps) != 0 {
+						t.Fatalf("rejected archive recorded %d steps", len(plan.Steps))
+					}
+					return
+				}
+
+				if len(plan.Steps) != 1 {
+					t.Fatalf("recorded %d steps, want 1", len(plan.Steps))
+				}
+				if got := len(plan.Conflicts()) > 0; got != tt.conflict {
+					t.Fatalf("conflict = %v, want %v", got, tt.conflict)
+				}
+				if _, err := os.Stat(out); tt.existing == "" && err == nil {
+					t.Fatal("dry run wrote to disk")
+				}
+
+				if err := plan.Exec(); err != nil {
+					t.Fatal(err)
+				}
+				if tt.opt.Exclude == nil && !FS(out).IsDir() {
+					t.Fatal("Exec did not extract")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package snake
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// reproEpoch 可重现模式下默认的修改时间，也是 zip 能表示的最早时间
+var reproEpoch = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// reproSpool 可重现模式下暂存条目内容，Close 时按名称排序写出
+// 内容暂存在临时文件中，不在内存中缓存。
+type reproSpool struct {
+	file    *os.File
+	size    int64
+	entries []spoolEntry
+}
+
+// spoolEntry 暂存的条目，write 写入条目头及内容
+type spoolEntry struct {
+	name   string
+	offset int64
+	size   int64
+	write  func(body io.Reader) error
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// add 暂存条目内容，r为空时内容为空，返回暂存的字节数
+func (s *reproSpool) add(name string, r io.Reader, write func(body io.Reader) error) (int64, error) {
+	entry := spoolEntry{name: name, offset: s.size, write: write}
+
+	if r != nil {
+		if s.file == nil {
+			f, err := os.CreateTemp("", "snake-spool-*")
+			if err != nil {
+				return 0, err
+			}
+			s.file = f
+		}
+
+		n, err := io.Copy(s.file, r)
+		s.size += n
+		if err != nil {
+			return n, err
+		}
+		entry.size = n
+	}
+
+	s.entries = append(s.entries, entry)
+	return entry.size, nil
+}
+
+// flush 按名称排序写出所有条目
+func (s *reproSpool) flush() error {
+	sort.SliceStable(s.entries, func(i, j int) bool { return s.entries[i].name < s.entries[j].name })
+
+	for _, entry := range s.entries {
+		var body io.Reader = io.NewSectionReader(s.file, entry.offset, entry.size)
+		if s.file == nil {
+			body = eofReader{}
+		}
+		if err := entry.write(body); err != nil {
+			return err
+		}
+	}
+	s.entries = nil
+	return nil
+}
+
+// close 删除临时文件
+func (s *reproSpool) close() {
+	if s.file != nil {
+		s.file.Close()
+		os.Remove(s.file.Name())
+		s.file = nil
+	}
+}
+
+// reproTime 返回可重现模式下条目的修改时间
+// 依次使用 t、环境变量 SOURCE_DATE_EPOCH 与 1980-01-01 UTC。
+func reproTime(t time.Time) time.Time {
+	if !t.IsZero() {
+		return t.UTC().Truncate(time.Second)
+	}
+	if v := os.Getenv("SOURCE_DATE_EPOCH"); v != "" {
+		if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(sec, 0).UTC()
+		}
+	}
+	return reproEpoch
+}
+
+// reproMode 统一权限：目录 0755，符号链接 0777，可执行文件 0755，其它文件 0644
+func reproMode(mode fs.FileMode) fs.FileMode {
+	switch {
+	case mode.IsDir():
+		return fs.ModeDir | 0755
+	case mode&fs.ModeSymlink != 0:
+		return fs.ModeSymlink | 0777
+	case mode&0111 != 0:
+		return mode.Type() | 0755
+	}
+	return mode.Type() | 0644
+}
+
+// reproHeader 统一 tar 条目头中的时间、属主与权限
+func reproHeader(header *tar.Header, modTime time.Time) {
+	header.ModTime, header.AccessTime, header.ChangeTime = modTime, time.Time{}, time.Time{}
+	header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+	header.Mode = int64(reproMode(header.FileInfo().Mode()).Perm())
+}
+
+// reproZipHeader 统一 zip 条目头中的时间与权限，zip 不能表示 1980 年以前的时间
+func reproZipHeader(header *zip.FileHeader, modTime time.Time) {
+	if modTime.Before(reproEpoch) {
+		modTime = reproEpoch
+	}
+	header.Modified = modTime
+	header.SetMode(reproMode(header.Mode()))
+}
+
+type eofReader struct{}
+
+func (eofReader) Read([]byte) (int, error) { return 0, io.EOF }
@@ -0,0 +1,198 @@
+package snake
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// reproEntry 可重现模式测试写入的条目
+type reproEntry struct {
+	name string
+	mode os.FileMode
+	body string
+}
+
+var reproEntries = []reproEntry{
+	{"b/", os.ModeDir | 0700, ""},
+	{"b/c.txt", 0600, "charlie"},
+	{"a.txt", 0664, "alpha"},
+	{"run.sh", 0700, "#!/bin/sh\n"},
+}
+
+// reproTar 按order的顺序写入条目，modTime与属主每次不同
+func reproTar(t *testing.T, opt TarOptions, modTime time.Time, order []int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tl := TarWriter(&buf, opt)
+	for _, i := range order {
+		e := reproEntries[i]
+		header := &tar.Header{Name: e.name, Mode: int64(e.mode.Perm()), Size: int64(len(e.body)), ModTime: modTime,
+			Typeflag: tar.TypeReg, Uid: modTime.Second(), Uname: modTime.String()}
+		if e.mode.IsDir() {
+			header.Typeflag = tar.TypeDir
+		}
+		if ok, err := tl.AddReader(e.name, header.FileInfo(), bytes.NewReader([]byte(e.body))); !ok {
+			t.Fatal(err)
+		}
+	}
+	if err := tl.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// reproZip 按order的顺序写入条目，modTime每次不同
+func reproZip(t *testing.T, opt ZipOptions, modTime time.Time, order []int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	z := ZipWriter(&buf, opt)
+	for _, i := range order {
+		e := reproEntries[i]
+		var body []byte
+		if !e.mode.IsDir() {
+			body = []byte(e.body)
+		}
+		if !z.Add(e.name, body, ZipEntryOptions{Modified: modTime, Mode: e.mode}) {
+			t.Fatalf("Add %s failed", e.name)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReproducibleWriter(t *testing.T) {
+	t1 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	t2 := time.Date(2023, 6, 7, 8, 9, 10, 0, time.Local)
+	order1, order2 := []int{0, 1, 2, 3}, []int{3, 2, 1, 0}
+
+	for _, codec := range []Codec{CodecNone, CodecGzip, CodecBzip2, CodecXz, CodecZstd} {
+		t.Run("tar/"+string(codec), func(t *testing.T) {
+			opt := TarOptions{Codec: codec, Reproducible: true, Manifest: HashSHA256}
+			a, b := reproTar(t, opt, t1, order1), reproTar(t, opt, t2, order2)
+			if !bytes.Equal(a, b) {
+				t.Fatal("output differs")
+			}
+		})
+	}
+
+	t.Run("zip", func(t *testing.T) {
+		opt := ZipOptions{Reproducible: true, Manifest: HashSHA256}
+		a, b := reproZip(t, opt, t1, order1), reproZip(t, opt, t2, order2)
+		if !bytes.Equal(a, b) {
+			t.Fatal("output differs")
+		}
+	})
+
+	// 未开启时输出不同
+	if bytes.Equal(reproTar(t, TarOptions{Codec: CodecNone}, t1, order1), reproTar(t, TarOptions{Codec: CodecNone}, t2, order2)) {
+		t.Fatal("non-reproducible output is identical")
+	}
+}
+
+// TestReproducibleHeaders 条目按名称排序，时间、属主与权限统一
+func TestReproducibleHeaders(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	b := reproTar(t, TarOptions{Codec: CodecNone, Reproducible: true, ModTime: modTime}, time.Now(), []int{3, 2, 1, 0})
+	tr := tar.NewReader(bytes.NewReader(b))
+	want := []struct {
+		name string
+		mode int64
+	}{{"a.txt", 0644}, {"b/", 0755}, {"b/c.txt", 0644}, {"run.sh", 0755}}
+	for _, w := range want {
+		header, err := tr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Name != w.name || header.Mode != w.mode || !header.ModTime.Equal(modTime) ||
+			header.Uid != 0 || header.Uname != "" {
+			t.Fatalf("header = %s %o %v %d %q, want %s %o %v", header.Name, header.Mode, header.ModTime,
+				header.Uid, header.Uname, w.name, w.mode, modTime)
+		}
+	}
+	if _, err := tr.Next(); err != io.EOF {
+		t.Fatalf("extra entries: %v", err)
+	}
+
+	b = reproZip(t, ZipOptions{Reproducible: true, ModTime: modTime}, time.Now(), []int{3, 2, 1, 0})
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, w := range want {
+		file := zr.File[i]
+		if file.Name != w.name || int64(file.Mode().Perm()) != w.mode || !file.Modified.Equal(modTime) {
+			t.Fatalf("zip entry = %s %v %v, want %s %o %v", file.Name, file.Mode(), file.Modified, w.name, w.mode, modTime)
+		}
+	}
+}
+
+func TestReproTime(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 600, time.FixedZone("CST", 8*3600))
+
+	tests := []struct {
+		name string
+		env  string
+		t    time.Time
+		want time.Time
+	}{
+		{"default", "", time.Time{}, reproEpoch},
+		{"option", "1700000000", modTime, modTime.UTC().Truncate(time.Second)},
+		{"SOURCE_DATE_EPOCH", "1700000000", time.Time{}, time.Unix(1700000000, 0).UTC()},
+		{"invalid SOURCE_DATE_EPOCH", "yesterday", time.Time{}, reproEpoch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SOURCE_DATE_EPOCH", tt.env)
+			if got := reproTime(tt.t); !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Fatalf("reproTime = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// zip 不能表示 1980 年以前的时间
+	header := &zip.FileHeader{Name: "a.txt"}
+	reproZipHeader(header, time.Unix(0, 0).UTC())
+	if !header.Modified.Equal(reproEpoch) {
+		t.Fatalf("zip modified = %v, want %v", header.Modified, reproEpoch)
+	}
+}
+
+// TestReproducibleArchive 修改时间与权限不同的相同目录归档后完全一致
+func TestReproducibleArchive(t *testing.T) {
+	for _, ext := range []string{".zip", ".tar.gz", ".tar.zst"} {
+		t.Run(ext, func(t *testing.T) {
+			opt := ArchiveOptions{TarOptions: TarOptions{Reproducible: true, Manifest: HashSHA256}}
+
+			dir1 := archiveTree(t)
+			target1 := filepath.Join(t.TempDir(), "out"+ext)
+			if err := FS(dir1).Archive(target1, opt); err != nil {
+				t.Fatal(err)
+			}
+
+			dir2 := archiveTree(t)
+			later := time.Now().Add(time.Hour)
+			os.Chtimes(filepath.Join(dir2, "a.txt"), later, later)
+			os.Chmod(filepath.Join(dir2, "sub", "b.txt"), 0600)
+			target2 := filepath.Join(t.TempDir(), "out"+ext)
+			if err := FS(dir2).Archive(target2, opt); err != nil {
+				t.Fatal(err)
+			}
+
+			a, _ := os.ReadFile(target1)
+			b, _ := os.ReadFile(target2)
+			if len(a) == 0 || !bytes.Equal(a, b) {
+				t.Fatal("output differs")
+			}
+		})
+	}
+}
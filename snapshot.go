@@ -0,0 +1,454 @@
+package snake
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// snapshotLayout 快照名称的时间格式，按名称排序即按时间排序
+const snapshotLayout = "20060102T150405.000Z"
+
+// Snapshot 快照仓库中的一个快照
+// 快照目录 <repo>/<ID> 是源目录的完整副本，未变化的文件与上一个快照共享硬链接；
+// 校验清单 <repo>/<ID>.SHA256SUMS 记录所有文件的摘要，可以在快照目录中用 sha256sum -c 校验。
+type Snapshot struct {
+	ID   string    // 快照名称，即 UTC 创建时间
+	Path string    // 快照目录
+	Time time.Time // 创建时间
+
+	// 以下统计只在 Snapshot() 返回时填写
+	Files  int   // 文件数
+	Copied int   // 新复制的文件数
+	Linked int   // 硬链接到上一个快照的文件数
+	Bytes  int64 // 新复制的字节数
+}
+
+// SnapshotOptions 创建快照的选项
+type SnapshotOptions struct {
+	Checksum bool         // 根据 SHA256 判断文件内容是否变化，默认比较大小与修改时间
+	Ignore   *IgnoreRules // 排除规则，为空时不排除任何路径
+}
+
+// SnapshotRepo 快照仓库，每个快照是仓库下的一个目录
+type SnapshotRepo struct {
+	Path string
+}
+
+// Retention 快照保留规则，满足任一规则的快照被保留
+// 按天、周、月分组时使用本地时间，每组保留最新的快照。
+type Retention struct {
+	Last    int // 保留最近的N个快照
+	Daily   int // 保留最近N天每天最新的快照
+	Weekly  int // 保留最近N周每周最新的快照
+	Monthly int // 保留最近N个月每月最新的快照
+}
+
+// ---------------------------------------
+// 输入 :
+
+// Snapshots 打开快照仓库，仓库目录在第一次创建快照时创建
+// 例子：
+// snake.Snapshots("/backup/site").Prune(snake.Retention{Daily: 7, Weekly: 4})
+func Snapshots(repo string) *SnapshotRepo {
+	return &SnapshotRepo{Path: filepath.Clean(repo)}
+}
+
+// ---------------------------------------
+// 处理 :
+
+// Snapshot 在快照仓库repo中为当前目录创建快照
+// 与最新快照相比大小、权限与修改时间（或 Checksum 时的摘要）未变化的文件以硬链接共享，只复制变化的文件。
+// 快照先写入临时目录，完成后重命名，中断时不会留下不完整的快照。
+// 保留文件权限、修改时间与符号链接，repo位于当前目录内时被跳过。
+// 例子：
+// snap, err := snake.FS("./site").Snapshot("/backup/site")
+func (sk *snakeFileSystem) Snapshot(repo string, opts ...SnapshotOptions) (*Snapshot, error) {
+	src, ok := sk.pathdst()
+	if !ok {
+		return nil, sk.err
+	}
+
+	if repo, ok = sk.pathdst(repo); !ok {
+		return nil, sk.err
+	}
+
+	if !sk.IsDir() {
+		return nil, fmt.Errorf("snake: %s is not a directory", src)
+	}
+
+	// 目录中的符号链接不能指向 Jail 根目录外
+	if err := sk.resolveTree(src); err != nil {
+		sk.err = err
+		return nil, err
+	}
+
+	var opt SnapshotOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	r := Snapshots(repo)
+	prev, err := r.Latest()
+	if err != nil {
+		return nil, err
+	}
+
+	// 上一个快照的摘要，缺少清单时全部重新复制
+	prevSums := map[string]string{}
+	if prev != nil {
+		if m, err := LoadManifest(r.manifest(prev.ID)); err == nil {
+			for _, v := range m.Entries {
+				prevSums[v.Path] = v.Sum
+			}
+		}
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	snap := &Snapshot{ID: now.Format(snapshotLayout), Time: now}
+	snap.Path = filepath.Join(repo, snap.ID)
+	if FS(snap.Path).Exist() {
+		return nil, fmt.Errorf("snake: snapshot %s already exists", snap.ID)
+	}
+
+	tmp := filepath.Join(repo, "."+snap.ID+".tmp")
+	if err := os.MkdirAll(tmp, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	sums := &Manifest{Algo: HashSHA256}
+	srcFS := os.DirFS(src)
+	absRepo, _ := filepath.Abs(repo)
+
+	// 目录的权限与修改时间在内容写入后设置
+	var dirs []string
+
+	err = filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		target := filepath.Join(tmp, rel)
+
+		if abs, _ := filepath.Abs(p); abs == absRepo {
+			return filepath.SkipDir
+		}
+
+		if _, ok := opt.Ignore.Match(name, info.IsDir()); ok {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch {
+		case info.IsDir():
+			dirs = append(dirs, rel)
+			return os.Mkdir(target, 0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case !info.Mode().IsRegular():
+			return nil
+		}
+
+		snap.Files++
+
+		// 未变化的文件硬链接到上一个快照，链接失败时复制
+		// 硬链接共享权限，权限变化的文件也需要复制
+		if sum, ok := prevSums[name]; ok {
+			old := filepath.Join(prev.Path, rel)
+			if oi, err := os.Lstat(old); err == nil && oi.Mode().IsRegular() && oi.Size() == info.Size() && oi.Mode().Perm() == info.Mode().Perm() {
+				same := oi.ModTime().Equal(info.ModTime())
+				if opt.Checksum {
+					cur, err := hashFile(srcFS, name, HashSHA256)
+					if err != nil {
+						return err
+					}
+					same = cur == sum
+				}
+				if same && os.Link(old, target) == nil {
+					sums.Entries = append(sums.Entries, ManifestEntry{Path: name, Sum: sum})
+					snap.Linked++
+					return nil
+				}
+			}
+		}
+
+		h, _ := newHash(HashSHA256)
+		n, err := copyFile(p, target, info, h)
+		if err != nil {
+			return err
+		}
+		sums.record(name, h)
+		snap.Copied++
+		snap.Bytes += n
+		return nil
+	})
+
+	for i := len(dirs) - 1; err == nil && i >= 0; i-- {
+		err = restoreAttr(filepath.Join(src, dirs[i]), filepath.Join(tmp, dirs[i]))
+	}
+
+	// 清单先于快照目录写入，快照目录存在时清单一定完整
+	if err == nil {
+		err = os.WriteFile(r.manifest(snap.ID), []byte(sums.sorted().String()), 0644)
+	}
+	if err == nil {
+		if err = os.Rename(tmp, snap.Path); err != nil {
+			os.Remove(r.manifest(snap.ID))
+		}
+	}
+
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	return snap, nil
+}
+
+// List 返回仓库中的所有快照，按时间从旧到新排序，仓库不存在时返回空
+func (r *SnapshotRepo) List() ([]Snapshot, error) {
+	entries, err := os.ReadDir(r.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var res []Snapshot
+	for _, v := range entries {
+		if !v.IsDir() {
+			continue
+		}
+		t, err := time.Parse(snapshotLayout, v.Name())
+		if err != nil {
+			continue
+		}
+		res = append(res, Snapshot{ID: v.Name(), Path: filepath.Join(r.Path, v.Name()), Time: t})
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
+// Latest 返回最新的快照，仓库中没有快照时返回空
+func (r *SnapshotRepo) Latest() (*Snapshot, error) {
+	list, err := r.List()
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return &list[len(list)-1], nil
+}
+
+// Restore 将快照id恢复到dst，保留权限、修改时间与符号链接
+// 未指定paths时恢复整个快照；paths为快照中的相对路径，可以是文件或目录，恢复到 dst/path。
+// 已存在的文件被覆盖，dst中多余的文件不会删除。恢复的文件是副本，修改它们不影响快照。
+// 例子：
+// snake.Snapshots("/backup/site").Restore(snap.ID, "./site", "config/app.yaml")
+func (r *SnapshotRepo) Restore(id, dst string, paths ...string) error {
+	snap, err := r.snapshot(id)
+	if err != nil {
+		return err
+	}
+
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	for _, v := range paths {
+		rel := filepath.Clean(filepath.FromSlash(v))
+		if !filepath.IsLocal(rel) && rel != "." {
+			return fmt.Errorf("snake: invalid snapshot path %s", v)
+		}
+
+		if err := copyTree(filepath.Join(snap.Path, rel), filepath.Join(dst, rel)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify 根据快照的校验清单校验快照内容
+func (r *SnapshotRepo) Verify(id string) (*ManifestReport, error) {
+	snap, err := r.snapshot(id)
+	if err != nil {
+		return nil, err
+	}
+	return FS(snap.Path).VerifyManifest(r.manifest(snap.ID))
+}
+
+// Prune 按保留规则删除快照，返回被删除的快照
+// 规则均为0时返回错误，不删除任何快照。删除快照不影响与其共享硬链接的其它快照。
+// 例子：
+// removed, err := snake.Snapshots("/backup/site").Prune(snake.Retention{Last: 3, Daily: 7, Weekly: 4})
+func (r *SnapshotRepo) Prune(keep Retention) ([]Snapshot, error) {
+	if keep == (Retention{}) {
+		return nil, errors.New("snake: empty snapshot retention policy")
+	}
+
+	list, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make(map[string]bool)
+	for i := len(list) - 1; i >= len(list)-keep.Last && i >= 0; i-- {
+		kept[list[i].ID] = true
+	}
+	retain(list, keep.Daily, kept, func(t time.Time) string { return t.Format("2006-01-02") })
+	retain(list, keep.Weekly, kept, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%d", year, week)
+	})
+	retain(list, keep.Monthly, kept, func(t time.Time) string { return t.Format("2006-01") })
+
+	var removed []Snapshot
+	for _, v := range list {
+		if kept[v.ID] {
+			continue
+		}
+		if err := os.RemoveAll(v.Path); err != nil {
+			return removed, err
+		}
+		os.Remove(r.manifest(v.ID))
+		removed = append(removed, v)
+	}
+	return removed, nil
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// snapshot 根据名称查找快照
+func (r *SnapshotRepo) snapshot(id string) (*Snapshot, error) {
+	t, err := time.Parse(snapshotLayout, id)
+	if err != nil {
+		return nil, fmt.Errorf("snake: invalid snapshot id %s", id)
+	}
+
+	snap := &Snapshot{ID: id, Path: filepath.Join(r.Path, id), Time: t}
+	if !FS(snap.Path).IsDir() {
+		return nil, fmt.Errorf("snake: snapshot %s not found", id)
+	}
+	return snap, nil
+}
+
+// manifest 返回快照校验清单的路径
+func (r *SnapshotRepo) manifest(id string) string {
+	return filepath.Join(r.Path, id+"."+ManifestName(HashSHA256))
+}
+
+// retain 从新到旧按分组保留每组最新的快照，最多保留n组
+func retain(list []Snapshot, n int, kept map[string]bool, group func(t time.Time) string) {
+	last := ""
+	for i := len(list) - 1; i >= 0 && n > 0; i-- {
+		if key := group(list[i].Time.Local()); key != last {
+			kept[list[i].ID] = true
+			last = key
+			n--
+		}
+	}
+}
+
+// copyTree 复制文件、目录或符号链接，保留权限与修改时间
+func copyTree(src, dst string) error {
+	var dirs []string
+
+	err := filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			dirs = append(dirs, rel)
+			return os.MkdirAll(target, 0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+				return err
+			}
+			os.Remove(target)
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+				return err
+			}
+			_, err := copyFile(p, target, info, nil)
+			return err
+		}
+		return nil
+	})
+
+	for i := len(dirs) - 1; err == nil && i >= 0; i-- {
+		err = restoreAttr(filepath.Join(src, dirs[i]), filepath.Join(dst, dirs[i]))
+	}
+	return err
+}
+
+// copyFile 复制文件内容、权限与修改时间，w不为空时同时写入w
+// dst已存在时先删除，不会修改与其共享硬链接的文件。
+func copyFile(src, dst string, info os.FileInfo, w io.Writer) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	if err := os.Remove(dst); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return 0, err
+	}
+
+	var r io.Reader = in
+	if w != nil {
+		r = io.TeeReader(in, w)
+	}
+
+	n, err := io.Copy(out, r)
+	if e := out.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = restoreAttr(src, dst)
+	}
+	return n, err
+}
+
+// restoreAttr 将src的权限与修改时间设置到dst
+func restoreAttr(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,73 @@
+package snake
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotIncremental(t *testing.T) {
+	tests := []struct {
+		name     string
+		checksum bool
+		change   func(t *testing.T, path string)
+		linked   bool
+		perm     os.FileMode // 第二个快照中文件的权限
+	}{
+		{name: "unchanged", change: func(t *testing.T, path string) {}, linked: true, perm: 0644},
+		{name: "unchanged checksum", checksum: true, change: func(t *testing.T, path string) {}, linked: true, perm: 0644},
+		{name: "content", change: func(t *testing.T, path string) {
+			os.WriteFile(path, []byte("new!"), 0644)
+			os.Chtimes(path, time.Now(), time.Now().Add(time.Hour))
+		}, perm: 0644},
+		{name: "chmod", change: func(t *testing.T, path string) {
+			os.Chmod(path, 0755)
+		}, perm: 0755},
+		{name: "chmod checksum", checksum: true, change: func(t *testing.T, path string) {
+			os.Chmod(path, 0755)
+		}, perm: 0755},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src, repo := filepath.Join(dir, "src"), filepath.Join(dir, "repo")
+			file := filepath.Join(src, "f.txt")
+			os.MkdirAll(src, 0755)
+			os.WriteFile(file, []byte("old!"), 0644)
+			os.Chmod(file, 0644)
+
+			opt := SnapshotOptions{Checksum: tt.checksum}
+			first, err := FS(src).Snapshot(repo, opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.change(t, file)
+			time.Sleep(2 * time.Millisecond)
+
+			second, err := FS(src).Snapshot(repo, opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := second.Linked == 1; got != tt.linked {
+				t.Fatalf("Linked = %d, Copied = %d, want linked %v", second.Linked, second.Copied, tt.linked)
+			}
+
+			info, err := os.Stat(filepath.Join(second.Path, "f.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != tt.perm {
+				t.Fatalf("mode = %v, want %v", info.Mode().Perm(), tt.perm)
+			}
+			if old, _ := os.Stat(filepath.Join(first.Path, "f.txt")); old.Mode().Perm() != 0644 {
+				t.Fatalf("first snapshot mode changed to %v", old.Mode().Perm())
+			}
+			if _, err := Snapshots(repo).Verify(second.ID); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
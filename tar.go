@@ -3,46 +3,219 @@ package snake
 import (
 	"archive/tar"
 	"bytes"
+	"hash"
+	"io"
 	"io/fs"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/dsnet/compress/bzip2"
 )
 
 type Tarlib struct {
-	Buffer   *bytes.Buffer
-	FS       *tar.Writer
+	// Deprecated: 条目直接写入目标，不再缓存在内存中，该字段始终为空。
+	Buffer *bytes.Buffer
+	// Deprecated: 使用 Compress。使用单线程 bzip2 压缩时与 Compress 相同，否则为空。
 	Gzip     *bzip2.Writer
+	FS       *tar.Writer
+	Compress io.WriteCloser // 压缩层，CodecNone 时为空
+	Codec    Codec          // 压缩方式
 	FileName string
+	Skipped  []SkippedEntry // 被排除规则跳过的条目
+	file     *pendingFile   // Tar() 写入的临时文件，Close 成功时重命名为 FileName
+	ignore   *IgnoreRules
+	repro    *reproSpool // 可重现模式下暂存的条目
+	modTime  time.Time   // 可重现模式下的修改时间
+	sums     *Manifest   // 已写入文件条目的校验清单
+	err      error
 }
 
-func Tar(tarfile string) *Tarlib {
-	t := new(Tarlib)
-	t.Buffer = new(bytes.Buffer)
-	t.Gzip, _ = bzip2.NewWriter(t.Buffer, &bzip2.WriterConfig{
-		Level: 9,
-	})
-	t.FS = tar.NewWriter(t.Gzip)
+// Tar 新建 tar 归档文件，条目添加时即写入同目录下的临时文件
+// Close 成功时重命名为tarfile，出错时删除临时文件，tarfile 保持不变。
+// 未指定压缩方式时根据扩展名推断：.tar、.tar.gz、.tar.bz2、.tar.xz、.tar.zst，无法推断时使用 bzip2。
+// 例子：
+// snake.Tar("artifact.tar.zst", snake.TarOptions{Level: 3, Threads: 4})
+func Tar(tarfile string, opts ...TarOptions) *Tarlib {
+	var opt TarOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Codec == "" {
+		opt.Codec = codecFromExt(tarfile)
+	}
+
+	f, err := createPending(tarfile)
+	if err != nil {
+		return &Tarlib{FileName: tarfile, err: err}
+	}
+	t := TarWriter(f, opt)
 	t.FileName = tarfile
+	t.file = f
 	return t
 }
 
-func (t *Tarlib) Add(path string, stat fs.FileInfo, body []byte) bool {
-	if !String(path).Find(".DS_Store", true) && !String(path).Find("__MACOSX", true) && !String(path).Find(".gitignore", true) && !String(path).Find(".index", true) {
-		header, _ := tar.FileInfoHeader(stat, path)
-		header.Name = filepath.ToSlash(path)
-		header.Size = int64(len(body))
-		if err := t.FS.WriteHeader(header); err == nil {
-			_, err := t.FS.Write(body)
-			return err == nil
+// TarWriter 新建写入w的 tar 归档，条目添加时即写入w，不在内存中缓存
+// 未指定压缩方式时使用 bzip2。
+// w 可以是 http.ResponseWriter，也可以是通过 FileSystem 打开的文件：
+// f, _ := snake.FS("out.tar.gz").MkFile()
+// t := snake.TarWriter(f, snake.TarOptions{Codec: snake.CodecGzip})
+func TarWriter(w io.Writer, opts ...TarOptions) *Tarlib {
+	var opt TarOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Codec == "" {
+		opt.Codec = CodecBzip2
+	}
+
+	t := &Tarlib{Codec: opt.Codec, ignore: opt.Ignore}
+	if opt.Reproducible {
+		t.repro, t.modTime = &reproSpool{}, reproTime(opt.ModTime)
+	}
+	if opt.Manifest != "" {
+		if _, t.err = newHash(opt.Manifest); t.err != nil {
+			return t
 		}
+		t.sums = &Manifest{Algo: opt.Manifest}
+	}
+	if t.Compress, t.err = compressor(w, opt); t.err != nil {
+		return t
 	}
-	return false
+
+	if t.Compress != nil {
+		t.Gzip, _ = t.Compress.(*bzip2.Writer)
+		t.FS = tar.NewWriter(t.Compress)
+	} else {
+		t.FS = tar.NewWriter(w)
+	}
+	return t
+}
+
+func (t *Tarlib) Add(path string, stat fs.FileInfo, body []byte) bool {
+	ok, _ := t.add(path, stat, "", int64(len(body)), bytes.NewReader(body))
+	return ok
+}
+
+// AddReader 从r读取条目内容，大小以stat.Size()为准
+// 条目被排除规则跳过时返回false与nil错误，并记录到 Skipped。
+func (t *Tarlib) AddReader(path string, stat fs.FileInfo, r io.Reader) (bool, error) {
+	return t.add(path, stat, "", stat.Size(), r)
+}
+
+// AddLink 添加指向target的符号链接
+func (t *Tarlib) AddLink(path string, stat fs.FileInfo, target string) (bool, error) {
+	return t.add(path, stat, target, 0, nil)
 }
 
 func (t *Tarlib) Close() error {
-	t.FS.Close()
-	t.Gzip.Close()
-	_, err := FS(t.FileName).ByteWriter(t.Buffer.Bytes())
+	if t.err != nil {
+		if t.repro != nil {
+			t.repro.close()
+		}
+		if t.file != nil {
+			t.file.commit(t.err)
+			t.file = nil
+		}
+		return t.err
+	}
+
+	var err error
+	if t.sums != nil {
+		err = t.addManifest()
+	}
+	if t.repro != nil {
+		if e := t.repro.flush(); err == nil {
+			err = e
+		}
+		t.repro.close()
+	}
+	if e := t.FS.Close(); err == nil {
+		err = e
+	}
+	if t.Compress != nil {
+		if e := t.Compress.Close(); err == nil {
+			err = e
+		}
+	}
+	if t.file != nil {
+		err = t.file.commit(err)
+		t.file = nil
+	}
+	return err
+}
+
+// add 写入条目头及size字节的内容，link为符号链接的目标
+func (t *Tarlib) add(path string, stat fs.FileInfo, link string, size int64, r io.Reader) (bool, error) {
+	header, err := tar.FileInfoHeader(stat, link)
+	if err != nil {
+		return false, err
+	}
+	header.Name = filepath.ToSlash(path)
+	if !stat.Mode().IsRegular() {
+		size = 0
+	}
+	header.Size = size
+	return t.addHeader(header, r)
+}
+
+// addHeader 写入条目头及 header.Size 字节的内容，排除规则、校验清单与可重现模式同样有效
+func (t *Tarlib) addHeader(header *tar.Header, r io.Reader) (bool, error) {
+	if t.err != nil {
+		return false, t.err
+	}
+
+	if rule, ok := t.ignore.Match(header.Name, header.Typeflag == tar.TypeDir); ok {
+		t.Skipped = append(t.Skipped, SkippedEntry{Path: header.Name, Rule: rule})
+		return false, nil
+	}
+
+	size := header.Size
+	var err error
+	var h hash.Hash
+	if t.sums != nil && header.Typeflag == tar.TypeReg {
+		if r == nil {
+			r = eofReader{}
+		}
+		r, h = t.sums.tee(r)
+	}
+
+	if t.repro == nil {
+		err = t.write(header, r)
+	} else {
+		reproHeader(header, t.modTime)
+		if r != nil {
+			r = io.LimitReader(r, size)
+		}
+		var n int64
+		n, err = t.repro.add(header.Name, r, func(body io.Reader) error { return t.write(header, body) })
+		if err == nil && n < size {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+
+	if err == nil && h != nil {
+		t.sums.record(header.Name, h)
+	}
+	return err == nil, err
+}
+
+// addManifest 写入校验清单条目
+func (t *Tarlib) addManifest() error {
+	sums := t.sums.sorted()
+	t.sums = nil
+
+	body := sums.String()
+	header := &tar.Header{Typeflag: tar.TypeReg, Name: ManifestName(sums.Algo), Mode: 0644, Size: int64(len(body)), ModTime: time.Now()}
+	_, err := t.add(header.Name, header.FileInfo(), "", header.Size, strings.NewReader(body))
+	return err
+}
+
+// write 写入条目头及内容
+func (t *Tarlib) write(header *tar.Header, r io.Reader) error {
+	if err := t.FS.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.CopyN(t.FS, r, header.Size)
 	return err
 }
@@ -0,0 +1,79 @@
+package snake
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/rand"
+	"io"
+	"strings"
+	"testing"
+)
+
+// regularHeader 返回大小为size的普通文件条目头
+func regularHeader(name string, size int64) *tar.Header {
+	return &tar.Header{Name: name, Mode: 0644, Size: size, Typeflag: tar.TypeReg}
+}
+
+func TestTarWriterStreams(t *testing.T) {
+	body := make([]byte, 1<<20)
+	rand.Read(body)
+
+	var buf bytes.Buffer
+	tl := TarWriter(&buf, TarOptions{Codec: CodecNone})
+	if ok, err := tl.AddReader("big.bin", regularHeader("big.bin", int64(len(body))).FileInfo(), bytes.NewReader(body)); !ok {
+		t.Fatal(err)
+	}
+	// 条目添加时即写入w，不等到 Close
+	if buf.Len() < len(body) {
+		t.Fatalf("%d bytes written before Close, want at least %d", buf.Len(), len(body))
+	}
+	if ok, err := tl.AddReader("small.txt", regularHeader("small.txt", 5).FileInfo(), strings.NewReader("small")); !ok {
+		t.Fatal(err)
+	}
+	if err := tl.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if tl.Buffer != nil {
+		t.Fatal("deprecated Buffer is set")
+	}
+
+	want := map[string]string{"big.bin": string(body), "small.txt": "small"}
+	tr := tar.NewReader(&buf)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(tr)
+		if string(b) != want[header.Name] {
+			t.Fatalf("%s: content mismatch", header.Name)
+		}
+		delete(want, header.Name)
+	}
+	if len(want) > 0 {
+		t.Fatalf("missing entries %v", want)
+	}
+}
+
+func TestTarAddReaderShort(t *testing.T) {
+	tests := []struct {
+		name         string
+		reproducible bool
+	}{
+		{"streaming", false},
+		{"reproducible", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl := TarWriter(io.Discard, TarOptions{Codec: CodecNone, Reproducible: tt.reproducible})
+			// 内容比 stat 中的大小短
+			ok, err := tl.AddReader("short.txt", regularHeader("short.txt", 10).FileInfo(), strings.NewReader("short"))
+			if ok || err == nil {
+				t.Fatal("short reader accepted")
+			}
+		})
+	}
+}
@@ -0,0 +1,513 @@
+package snake
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Transaction 文件操作事务
+// 将多个 FileSystem 操作（Write、Mv、Rn、Rm、Cp、MkDir、Extract）组合为一个事务，
+// 每一步执行前先把撤销方式写入磁盘日志，任一步骤失败时自动回滚已完成的步骤，
+// 进程崩溃后可通过 Recover 读取日志恢复现场。
+// 日志目录需与操作目标位于同一文件系统，被覆盖或删除的内容会移动到日志目录中备份。
+// 例子：
+// tx, err := snake.Begin(".release-journal")
+// tx.FS("release/app.conf").Write(conf)
+// tx.FS("release/current").Rn("previous")
+// err = tx.Commit()
+type Transaction struct {
+	Journal string // 日志目录
+	steps   []txStep
+	err     error
+	done    bool
+}
+
+// txStep 日志中的一条记录，描述如何撤销一步操作
+type txStep struct {
+	Op      string `json:"op"`                // 操作类型
+	Path    string `json:"path,omitempty"`    // 操作后的目标路径
+	From    string `json:"from,omitempty"`    // 移动前的原路径
+	Backup  string `json:"backup,omitempty"`  // 被覆盖或删除内容的备份路径
+	Dir     string `json:"dir,omitempty"`     // 本步骤新建的最上层目录
+	Created bool   `json:"created,omitempty"` // Path 由本步骤新建，回滚时删除
+}
+
+const (
+	txOpWrite  = "write"
+	txOpRm     = "rm"
+	txOpMv     = "mv"
+	txOpCp     = "cp"
+	txOpMkDir  = "mkdir"
+	txOpCommit = "commit"
+
+	txJournalFile = "journal"
+	txBackupDir   = "backup"
+)
+
+// ErrTxDone 事务已提交或已回滚
+var ErrTxDone = errors.New("snake: transaction has already been committed or rolled back")
+
+// ---------------------------------------
+// 输入 :
+
+// Begin 开启事务，journal 为日志目录
+// 日志目录中存在未完成的事务时返回错误，需先调用 Recover。
+func Begin(journal string) (*Transaction, error) {
+	tx := &Transaction{Journal: filepath.Clean(journal)}
+
+	if FS(tx.journalFile()).Exist() {
+		return nil, fmt.Errorf("snake: unfinished transaction in %s, call Recover first", tx.Journal)
+	}
+
+	if err := os.MkdirAll(filepath.Join(tx.Journal, txBackupDir), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(tx.journalFile(), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx, f.Close()
+}
+
+// Atomic 在事务中执行fn，fn返回错误或任一步骤失败时回滚，否则提交
+func Atomic(journal string, fn func(tx *Transaction) error) error {
+	tx, err := Begin(journal)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if !tx.done {
+			tx.Rollback()
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Recover 根据日志恢复崩溃前未完成的事务
+// 未提交的事务会被回滚，已提交的事务仅清理日志。
+func Recover(journal string) error {
+	tx := &Transaction{Journal: filepath.Clean(journal)}
+
+	f, err := os.Open(tx.journalFile())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	committed := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var step txStep
+		if err := json.Unmarshal(scanner.Bytes(), &step); err != nil {
+			// 崩溃时写了一半的记录，对应的操作尚未执行
+			continue
+		}
+		if step.Op == txOpCommit {
+			committed = true
+			continue
+		}
+		tx.steps = append(tx.steps, step)
+	}
+	f.Close()
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if committed {
+		tx.done = true
+		return os.RemoveAll(tx.Journal)
+	}
+
+	return tx.Rollback()
+}
+
+// FS 返回属于当前事务的 FileSystem
+func (tx *Transaction) FS(str ...string) FileSystem {
+	sk := &snakeFileSystem{tx: tx}
+	return sk.Add(str...)
+}
+
+// ---------------------------------------
+// 输出 :
+
+// Commit 提交事务，清理日志与备份
+func (tx *Transaction) Commit() error {
+	if tx.err != nil {
+		return tx.err
+	}
+
+	if tx.done {
+		return ErrTxDone
+	}
+
+	if err := tx.record(txStep{Op: txOpCommit}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	tx.done = true
+	return os.RemoveAll(tx.Journal)
+}
+
+// Rollback 按相反顺序撤销已完成的步骤
+func (tx *Transaction) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+
+	tx.done = true
+
+	var err error
+	for i := len(tx.steps) - 1; i >= 0; i-- {
+		if e := tx.undo(tx.steps[i]); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	if err != nil {
+		// 保留日志，以便再次调用 Recover
+		return err
+	}
+
+	tx.steps = nil
+	return os.RemoveAll(tx.Journal)
+}
+
+// Err 返回导致事务自动回滚的错误
+func (tx *Transaction) Err() error {
+	return tx.err
+}
+
+// ---------------------------------------
+// 事务操作 :
+
+// write 写入文件，原文件存在时先备份
+// 目标已存在但不是普通文件（目录、符号链接等）时返回错误，不记录日志。
+func (tx *Transaction) write(path string, src []byte, add ...bool) (bool, error) {
+	if err := tx.check(); err != nil {
+		return false, err
+	}
+
+	step := txStep{Op: txOpWrite, Path: path, Dir: missingDir(filepath.Dir(path))}
+
+	info, err := os.Lstat(path)
+	switch {
+	case os.IsNotExist(err):
+		step.Created = true
+	case err != nil:
+		return false, tx.fail(err)
+	case !info.Mode().IsRegular():
+		return false, tx.fail(fmt.Errorf("snake: write %s: not a regular file", path))
+	default:
+		// 先完成备份再写日志，崩溃时日志中不会出现不完整的备份
+		step.Backup = tx.backupPath()
+		if !_owcpfile(FS(path), FS(step.Backup)) {
+			return false, tx.fail(fmt.Errorf("snake: backup %s failed", path))
+		}
+	}
+
+	if err := tx.record(step); err != nil {
+		return false, tx.fail(err)
+	}
+
+	if ok, err := FS(path).ByteWriter(src, add...); !ok {
+		if err == nil {
+			err = fmt.Errorf("snake: write %s failed", path)
+		}
+		return false, tx.fail(err)
+	}
+
+	return true, nil
+}
+
+// rm 将目录或文件移动到备份目录
+func (tx *Transaction) rm(path string) bool {
+	if tx.check() != nil {
+		return false
+	}
+
+	if !FS(path).Exist() {
+		return true
+	}
+
+	step := txStep{Op: txOpRm, Path: path, Backup: tx.backupPath()}
+	if err := tx.record(step); err != nil {
+		tx.fail(err)
+		return false
+	}
+
+	if err := os.Rename(path, step.Backup); err != nil {
+		tx.fail(err)
+		return false
+	}
+
+	return true
+}
+
+// rename 移动目录或文件，目标存在时先备份
+func (tx *Transaction) rename(from, to string) bool {
+	if tx.check() != nil {
+		return false
+	}
+
+	// 移动到原位置，与 os.Rename 相同不做任何操作
+	if filepath.Clean(from) == filepath.Clean(to) && lexists(from) {
+		return true
+	}
+
+	step := txStep{Op: txOpMv, Path: to, From: from, Dir: missingDir(filepath.Dir(to))}
+	if lexists(to) {
+		step.Backup = tx.backupPath()
+	}
+
+	if err := tx.record(step); err != nil {
+		tx.fail(err)
+		return false
+	}
+
+	if step.Backup != "" {
+		if err := os.Rename(to, step.Backup); err != nil {
+			tx.fail(err)
+			return false
+		}
+	}
+
+	if err := os.Rename(from, to); err != nil {
+		tx.fail(err)
+		return false
+	}
+
+	return true
+}
+
+// cp 拷贝目录或文件到dst，dst存在时先备份
+func (tx *Transaction) cp(src FileSystem, dst string) bool {
+	if tx.check() != nil {
+		return false
+	}
+
+	step := txStep{Op: txOpCp, Path: dst, Dir: missingDir(filepath.Dir(dst))}
+	if lexists(dst) {
+		step.Backup = tx.backupPath()
+	}
+
+	if err := tx.record(step); err != nil {
+		tx.fail(err)
+		return false
+	}
+
+	if step.Backup != "" {
+		if err := os.Rename(dst, step.Backup); err != nil {
+			tx.fail(err)
+			return false
+		}
+	}
+
+	if !_cp(FS(src.Get()), FS(dst)) {
+		tx.fail(fmt.Errorf("snake: copy %s to %s failed", src.Get(), dst))
+		return false
+	}
+
+	return true
+}
+
+// mkdir 创建目录
+func (tx *Transaction) mkdir(path string) bool {
+	if tx.check() != nil {
+		return false
+	}
+
+	dir := missingDir(path)
+	if dir == "" {
+		return FS(path).IsDir()
+	}
+
+	if err := tx.record(txStep{Op: txOpMkDir, Dir: dir}); err != nil {
+		tx.fail(err)
+		return false
+	}
+
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		tx.fail(err)
+		return false
+	}
+
+	return true
+}
+
+// extract 先通过fn解压到日志目录中的临时目录，完成后移动到dst
+// 事务不支持向已存在的目录合并解压，dst已存在时返回错误。
+func (tx *Transaction) extract(dst string, fn func(dir string) error) error {
+	if err := tx.check(); err != nil {
+		return err
+	}
+
+	if lexists(dst) {
+		return tx.fail(fmt.Errorf("snake: extract to existing %s is not supported in a transaction", dst))
+	}
+
+	if !tx.mkdir(filepath.Dir(dst)) {
+		return tx.err
+	}
+
+	// 临时目录位于日志目录中，崩溃后随日志一起清理
+	tmp := filepath.Join(tx.Journal, txBackupDir, fmt.Sprint(len(tx.steps))+".extract")
+	if err := os.MkdirAll(tmp, os.ModePerm); err != nil {
+		return tx.fail(err)
+	}
+
+	if err := fn(tmp); err != nil {
+		os.RemoveAll(tmp)
+		return tx.fail(err)
+	}
+
+	if !tx.rename(tmp, dst) {
+		os.RemoveAll(tmp)
+		return tx.err
+	}
+	return nil
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// undo 撤销一步操作，可重复执行
+func (tx *Transaction) undo(step txStep) error {
+	var err error
+
+	switch step.Op {
+	case txOpWrite:
+		if step.Backup != "" && lexists(step.Backup) {
+			err = os.Rename(step.Backup, step.Path)
+		} else if step.Created {
+			// 只删除本步骤新建的文件，不会删除同名的目录
+			if err = os.Remove(step.Path); os.IsNotExist(err) {
+				err = nil
+			}
+		}
+	case txOpRm:
+		err = restore(step.Backup, step.Path)
+	case txOpMv:
+		if step.Backup != "" && !lexists(step.Backup) {
+			// 原目标尚未移入备份或已经恢复，移动没有执行
+			break
+		}
+		if lexists(step.Path) {
+			if lexists(step.From) {
+				err = fmt.Errorf("snake: cannot move %s back: %s already exists", step.Path, step.From)
+			} else {
+				err = os.Rename(step.Path, step.From)
+			}
+		}
+		if err == nil && step.Backup != "" {
+			err = restore(step.Backup, step.Path)
+		}
+	case txOpCp:
+		if step.Backup == "" || lexists(step.Backup) {
+			err = os.RemoveAll(step.Path)
+		}
+		if err == nil && step.Backup != "" {
+			err = restore(step.Backup, step.Path)
+		}
+	}
+
+	if err == nil && step.Dir != "" {
+		err = os.RemoveAll(step.Dir)
+	}
+
+	return err
+}
+
+// record 追加日志并落盘
+func (tx *Transaction) record(step txStep) error {
+	b, err := json.Marshal(step)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(tx.journalFile(), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(append(b, '\n')); err == nil {
+		err = f.Sync()
+	}
+
+	if e := f.Close(); err == nil {
+		err = e
+	}
+
+	if err == nil && step.Op != txOpCommit {
+		tx.steps = append(tx.steps, step)
+	}
+
+	return err
+}
+
+// check 判断事务是否仍可执行操作
+func (tx *Transaction) check() error {
+	if tx.err != nil {
+		return tx.err
+	}
+	if tx.done {
+		return ErrTxDone
+	}
+	return nil
+}
+
+// fail 记录错误并回滚已完成的步骤
+func (tx *Transaction) fail(err error) error {
+	tx.err = err
+	tx.Rollback()
+	return err
+}
+
+func (tx *Transaction) journalFile() string {
+	return filepath.Join(tx.Journal, txJournalFile)
+}
+
+func (tx *Transaction) backupPath() string {
+	return filepath.Join(tx.Journal, txBackupDir, fmt.Sprint(len(tx.steps)))
+}
+
+// restore 将备份移回path，备份不存在时不做任何操作
+// path已被其它内容占用时返回错误，不覆盖也不跳过，日志保留以便处理后再次调用 Recover。
+func restore(backup, path string) error {
+	if !lexists(backup) {
+		return nil
+	}
+	if lexists(path) {
+		return fmt.Errorf("snake: cannot restore %s: path already exists", path)
+	}
+	return os.Rename(backup, path)
+}
+
+// lexists 判断路径是否存在，不跟随符号链接
+func lexists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// missingDir 返回path中不存在的最上层目录，目录已存在时返回空
+func missingDir(path string) string {
+	dir := ""
+	for p := filepath.Clean(path); !FS(p).Exist(); p = filepath.Dir(p) {
+		dir = p
+		if p == filepath.Dir(p) {
+			break
+		}
+	}
+	return dir
+}
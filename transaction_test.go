@@ -0,0 +1,402 @@
+package snake
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// txTree 创建事务测试使用的目录，返回工作目录与日志目录
+func txTree(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range map[string]string{"a.txt": "old", "b.txt": "b", "d/f.txt": "f"} {
+		if !FS(dir, name).Write(body) {
+			t.Fatalf("write %s failed", name)
+		}
+	}
+	return dir, filepath.Join(dir, ".journal")
+}
+
+// checkTree 校验目录与回滚前一致，日志目录已清理
+func checkTree(t *testing.T, dir, journal string) {
+	t.Helper()
+	for name, want := range map[string]string{"a.txt": "old", "b.txt": "b", "d/f.txt": "f"} {
+		if b, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(b) != want {
+			t.Errorf("%s = %q, %v, want %q", name, b, err, want)
+		}
+	}
+	for _, name := range []string{"c.txt", "new.txt", "e", "copy"} {
+		if FS(dir, name).Exist() {
+			t.Errorf("%s should have been rolled back", name)
+		}
+	}
+	if FS(journal).Exist() {
+		t.Error("journal should be removed")
+	}
+}
+
+// txSteps 在事务中执行一组会被回滚的操作
+func txSteps(t *testing.T, tx *Transaction, dir string) {
+	t.Helper()
+	steps := []struct {
+		name string
+		ok   bool
+	}{
+		{"write", tx.FS(dir, "a.txt").Write("new")},
+		{"write new", tx.FS(dir, "new.txt").Write("x")},
+		{"rn", tx.FS(dir, "b.txt").Rn("c.txt")},
+		{"mkdir", tx.FS(dir, "e", "f").MkDir()},
+		{"cp", tx.FS(dir, "d").Cp(filepath.Join(dir, "copy"), true)},
+		{"rm", tx.FS(dir, "d").Rm()},
+	}
+	for _, v := range steps {
+		if !v.ok {
+			t.Fatalf("%s failed: %v", v.name, tx.Err())
+		}
+	}
+}
+
+func TestAtomicRollback(t *testing.T) {
+	injected := errors.New("injected")
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, tx *Transaction, dir string) error
+		want error
+	}{
+		{
+			name: "fn returns error",
+			fn: func(t *testing.T, tx *Transaction, dir string) error {
+				txSteps(t, tx, dir)
+				return injected
+			},
+			want: injected,
+		},
+		{
+			name: "step fails",
+			fn: func(t *testing.T, tx *Transaction, dir string) error {
+				txSteps(t, tx, dir)
+				if tx.FS(dir, "missing").Mv(dir) {
+					t.Fatal("moving a missing file should fail")
+				}
+				if tx.Err() == nil {
+					t.Fatal("failed step should set Err")
+				}
+				return tx.Err()
+			},
+		},
+		{
+			name: "steps after failure are refused",
+			fn: func(t *testing.T, tx *Transaction, dir string) error {
+				txSteps(t, tx, dir)
+				tx.FS(dir, "missing").Mv(dir)
+				if tx.FS(dir, "a.txt").Write("late") {
+					t.Fatal("write after a failed step should be refused")
+				}
+				return tx.Err()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, journal := txTree(t)
+			err := Atomic(journal, func(tx *Transaction) error { return tt.fn(t, tx, dir) })
+			if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Fatalf("Atomic error = %v, want %v", err, tt.want)
+			}
+			checkTree(t, dir, journal)
+		})
+	}
+}
+
+func TestAtomicCommit(t *testing.T) {
+	dir, journal := txTree(t)
+	err := Atomic(journal, func(tx *Transaction) error {
+		txSteps(t, tx, dir)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(b) != "new" {
+		t.Errorf("a.txt = %q, want new", b)
+	}
+	for _, name := range []string{"c.txt", "new.txt", "e/f", "copy/d/f.txt"} {
+		if !FS(dir, name).Exist() {
+			t.Errorf("%s should exist", name)
+		}
+	}
+	for _, name := range []string{"b.txt", "d", ".journal"} {
+		if FS(dir, name).Exist() {
+			t.Errorf("%s should not exist", name)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name      string
+		tail      string // 崩溃时追加到日志末尾的内容
+		committed bool
+	}{
+		{name: "half-written record", tail: `{"op":"write","pa`},
+		{name: "record without operation", tail: `{"op":"write","path":"$DIR/never.txt"}` + "\n"},
+		{name: "clean crash"},
+		{name: "committed", tail: `{"op":"commit"}` + "\n", committed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, journal := txTree(t)
+
+			// 模拟执行到一半时进程退出：不调用 Commit 或 Rollback
+			tx, err := Begin(journal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			txSteps(t, tx, dir)
+
+			if tt.tail != "" {
+				f, err := os.OpenFile(filepath.Join(journal, txJournalFile), os.O_APPEND|os.O_WRONLY, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				f.WriteString(String(tt.tail).Replace("$DIR", filepath.ToSlash(dir), true).Get())
+				f.Close()
+			}
+
+			if _, err := Begin(journal); err == nil {
+				t.Fatal("Begin should refuse an unfinished journal")
+			}
+
+			if err := Recover(journal); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.committed {
+				if b, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(b) != "new" {
+					t.Errorf("committed write was rolled back: %q", b)
+				}
+				if FS(journal).Exist() {
+					t.Error("journal should be removed")
+				}
+				return
+			}
+			checkTree(t, dir, journal)
+
+			// 再次恢复不做任何操作
+			if err := Recover(journal); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestTxWriteNonRegular(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string // 写入的路径，已存在且不是普通文件
+		setup  func(t *testing.T, dir string)
+	}{
+		{name: "directory", target: "keep", setup: func(t *testing.T, dir string) {
+			FS(dir, "keep", "precious").Write("data")
+		}},
+		{name: "symlink", target: "link", setup: func(t *testing.T, dir string) {
+			FS(dir, "keep", "precious").Write("data")
+			symlink(t, "keep/precious", filepath.Join(dir, "link"))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, journal := txTree(t)
+			tt.setup(t, dir)
+
+			err := Atomic(journal, func(tx *Transaction) error {
+				txSteps(t, tx, dir)
+				if tx.FS(dir, tt.target).Write("x") {
+					t.Fatal("writing over a non-regular file should fail")
+				}
+				return tx.Err()
+			})
+			if err == nil {
+				t.Fatal("Atomic should return the write error")
+			}
+			checkTree(t, dir, journal)
+
+			if b, err := os.ReadFile(filepath.Join(dir, "keep", "precious")); err != nil || string(b) != "data" {
+				t.Fatalf("existing content lost: %q, %v", b, err)
+			}
+			if _, err := os.Lstat(filepath.Join(dir, tt.target)); err != nil {
+				t.Fatalf("%s removed by rollback: %v", tt.target, err)
+			}
+		})
+	}
+}
+
+func TestTxRenameOverExisting(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string // 已存在的目标
+	}{
+		{name: "file over file", from: "a.txt", to: "b.txt"},
+		{name: "directory over directory", from: "d", to: "keep"},
+		{name: "file over symlink", from: "a.txt", to: "link"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, journal := txTree(t)
+			FS(dir, "keep", "precious").Write("data")
+			symlink(t, "keep", filepath.Join(dir, "link"))
+
+			err := Atomic(journal, func(tx *Transaction) error {
+				if !tx.FS(dir, tt.from).Rn(tt.to) {
+					t.Fatalf("rename failed: %v", tx.Err())
+				}
+				return errors.New("abort")
+			})
+			if err == nil {
+				t.Fatal("Atomic should return the error")
+			}
+			checkTree(t, dir, journal)
+
+			if b, err := os.ReadFile(filepath.Join(dir, "keep", "precious")); err != nil || string(b) != "data" {
+				t.Fatalf("existing content lost: %q, %v", b, err)
+			}
+			if target, err := os.Readlink(filepath.Join(dir, "link")); err != nil || target != "keep" {
+				t.Fatalf("symlink = %q, %v", target, err)
+			}
+		})
+	}
+}
+
+func TestTxRestoreConflict(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(tx *Transaction, dir string) bool
+		path string // 操作完成后被其它进程占用的路径
+	}{
+		{name: "rm", op: func(tx *Transaction, dir string) bool { return tx.FS(dir, "a.txt").Rm() }, path: "a.txt"},
+		{name: "mv", op: func(tx *Transaction, dir string) bool { return tx.FS(dir, "a.txt").Rn("c.txt") }, path: "a.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, journal := txTree(t)
+			tx, err := Begin(journal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.op(tx, dir) {
+				t.Fatal(tx.Err())
+			}
+			os.WriteFile(filepath.Join(dir, tt.path), []byte("intruder"), 0644)
+
+			if err := tx.Rollback(); err == nil {
+				t.Fatal("Rollback should report the occupied path")
+			}
+			if b, _ := os.ReadFile(filepath.Join(dir, tt.path)); string(b) != "intruder" {
+				t.Fatalf("occupied path overwritten: %q", b)
+			}
+			if !FS(journal, txJournalFile).Exist() {
+				t.Fatal("journal should be kept for Recover")
+			}
+
+			// 处理冲突后可以再次恢复
+			os.Remove(filepath.Join(dir, tt.path))
+			if err := Recover(journal); err != nil {
+				t.Fatal(err)
+			}
+			checkTree(t, dir, journal)
+		})
+	}
+}
+
+func TestTxExtract(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string // 相对测试目录的解压目录
+		abort  bool
+		fail   bool // 解压目录已存在，应返回错误
+	}{
+		{name: "commit", target: "new/out"},
+		{name: "rollback", target: "new/out", abort: true},
+		{name: "existing target", target: "d", fail: true},
+	}
+
+	for _, tt := range tests {
+		for _, format := range []string{"zip", "tar"} {
+			t.Run(tt.name+"/"+format, func(t *testing.T) {
+				dir, journal := txTree(t)
+				src := filepath.Join(t.TempDir(), "a."+format)
+				entries := []testEntry{{name: "x/y.txt", body: "y"}, {name: "l", link: "x/y.txt"}}
+				if format == "zip" {
+					writeZip(t, src, entries)
+				} else {
+					writeTar(t, src, false, entries)
+				}
+
+				out := filepath.Join(dir, tt.target)
+				err := Atomic(journal, func(tx *Transaction) error {
+					if err := tx.FS(src).Extract(out); err != nil {
+						return err
+					}
+					if tt.abort {
+						return errors.New("abort")
+					}
+					return nil
+				})
+
+				if tt.fail || tt.abort {
+					if err == nil {
+						t.Fatal("Atomic should return an error")
+					}
+					checkTree(t, dir, journal)
+					if FS(dir, "new").Exist() {
+						t.Error("created parent directory should be rolled back")
+					}
+					return
+				}
+
+				if err != nil {
+					t.Fatal(err)
+				}
+				if b, err := os.ReadFile(filepath.Join(out, "l")); err != nil || string(b) != "y" {
+					t.Fatalf("extracted content = %q, %v", b, err)
+				}
+				if FS(journal).Exist() {
+					t.Error("journal should be removed")
+				}
+			})
+		}
+	}
+}
+
+func TestTxUnzip(t *testing.T) {
+	dir, journal := txTree(t)
+	src := filepath.Join(dir, "up.zip")
+	writeZip(t, src, []testEntry{{name: "x/y.txt", body: "y"}})
+
+	err := Atomic(journal, func(tx *Transaction) error {
+		if _, err := tx.FS(src).Unzip(); err != nil {
+			return err
+		}
+		if !FS(dir, "up", "x", "y.txt").IsFile() {
+			t.Fatal("Unzip did not extract")
+		}
+		return errors.New("abort")
+	})
+	if err == nil {
+		t.Fatal("Atomic should return the error")
+	}
+	checkTree(t, dir, journal)
+	if FS(dir, "up").Exist() {
+		t.Fatal("unzipped directory should be rolled back")
+	}
+}
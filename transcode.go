@@ -0,0 +1,231 @@
+package snake
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// binarySniff 判断二进制文件时检查的字节数，与 git 一致
+const binarySniff = 8000
+
+// TranscodeOptions 批量转换编码的选项
+type TranscodeOptions struct {
+	To      string   // 目标编码，为空时为 UTF-8，UTF-8-BOM、UTF-16 等写入 BOM
+	From    string   // 源编码，为空时逐个文件检测
+	Include []string // 只转换匹配的路径，如 *.go、*.txt，为空时转换全部文本文件
+	Exclude []string // 不转换匹配的路径，匹配目录时包括目录下的所有文件
+	Output  string   // 镜像目录，写入转换结果并原样复制其它文件，为空时原地转换
+}
+
+// TranscodeReport 批量转换编码的结果，路径均为相对路径
+type TranscodeReport struct {
+	Converted []TranscodeFile // 已转换的文件
+	Unchanged []string        // 已是目标编码的文件
+	Binary    []string        // 跳过的二进制文件
+	Failed    []TranscodeFile // 不能确定编码或不能转换的文件，保持原样
+}
+
+// TranscodeFile 单个文件的转换结果
+type TranscodeFile struct {
+	Path    string // 相对路径
+	Charset string // 检测到的源编码
+	Err     error  // 失败原因
+}
+
+// ---------------------------------------
+// 处理 :
+
+// Transcode 将目录下的文本文件转换为目标编码
+// 每个文件根据 BOM、UTF-8 校验与 Charset() 检测编码，解码后能原样编码回去才视为检测可信，
+// 否则记入 Failed 并保持原样。含 NUL 字节的文件视为二进制文件跳过（有 UTF-16 BOM 的除外）。
+// 原地转换通过 ByteWriter 写入，支持事务与 DryRun；有文件失败时同时返回报告与错误。
+// 例子：
+// report, err := snake.FS("./legacy").Transcode(snake.TranscodeOptions{Include: []string{"*.go", "*.txt"}})
+func (sk *snakeFileSystem) Transcode(opts ...TranscodeOptions) (*TranscodeReport, error) {
+	root, ok := sk.pathdst()
+	if !ok {
+		return nil, sk.err
+	}
+
+	if !sk.IsDir() {
+		return nil, fmt.Errorf("snake: %s is not a directory", root)
+	}
+
+	var opt TranscodeOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.To == "" {
+		opt.To = "UTF-8"
+	}
+	if _, err := textEncoding(opt.To); err != nil {
+		return nil, err
+	}
+
+	output := root
+	if opt.Output != "" {
+		if output, ok = sk.pathdst(opt.Output); !ok {
+			return nil, sk.err
+		}
+	}
+
+	// 目录中的符号链接不能指向 Jail 根目录外
+	if err := sk.resolveTree(root); err != nil {
+		sk.err = err
+		return nil, err
+	}
+
+	report := &TranscodeReport{}
+	absOutput, _ := filepath.Abs(output)
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		// 镜像目录位于源目录内时跳过
+		if abs, _ := filepath.Abs(path); opt.Output != "" && abs == absOutput {
+			return filepath.SkipDir
+		}
+
+		// 原地转换时跳过排除的目录，镜像目录中排除的文件原样复制
+		if info.IsDir() && opt.Output == "" && matchPath(opt.Exclude, name) {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		out, changed := body, false
+		selected := !matchPath(opt.Exclude, name) && (len(opt.Include) == 0 || matchPath(opt.Include, name))
+
+		switch {
+		case !selected:
+		case isBinary(body):
+			report.Binary = append(report.Binary, name)
+		default:
+			charset, converted, err := transcode(body, opt.From, opt.To)
+			switch {
+			case err != nil:
+				report.Failed = append(report.Failed, TranscodeFile{Path: name, Charset: charset, Err: err})
+			case bytes.Equal(converted, body):
+				report.Unchanged = append(report.Unchanged, name)
+			default:
+				report.Converted = append(report.Converted, TranscodeFile{Path: name, Charset: charset})
+				out, changed = converted, true
+			}
+		}
+
+		// 原地转换时只写入转换的文件，镜像目录中写入所有文件
+		if opt.Output == "" && !changed {
+			return nil
+		}
+		return sk.writeAs(filepath.Join(output, rel), out, info)
+	})
+	if err != nil {
+		return report, err
+	}
+
+	if len(report.Failed) > 0 {
+		return report, fmt.Errorf("snake: %d files could not be transcoded to %s", len(report.Failed), opt.To)
+	}
+	return report, nil
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// writeAs 通过当前 FileSystem 的事务或计划写入文件，并保留原文件的权限
+func (sk *snakeFileSystem) writeAs(path string, body []byte, info os.FileInfo) error {
+	w := &snakeFileSystem{Path: path, tx: sk.tx, plan: sk.plan, root: sk.root}
+	if _, err := w.ByteWriter(body); err != nil {
+		return err
+	}
+	if sk.plan == nil {
+		return os.Chmod(path, info.Mode().Perm())
+	}
+	return nil
+}
+
+// transcode 检测body的编码并转换为to，返回检测到的编码
+func transcode(body []byte, from, to string) (string, []byte, error) {
+	charset, text, err := decodeBody(body, from)
+	if err != nil {
+		return charset, nil, err
+	}
+
+	out, err := encodeText(text, to)
+	if err != nil {
+		return charset, nil, err
+	}
+	if bom := charsetBOM(to); bom != nil {
+		out = append(append([]byte{}, bom...), out...)
+	}
+	return charset, out, nil
+}
+
+// decodeBody 检测body的编码并转换为 UTF-8，from不为空时使用from
+// 解码结果能原样编码为body时才认为检测可信。
+func decodeBody(body []byte, from string) (string, string, error) {
+	switch {
+	case bytes.HasPrefix(body, bomUTF8):
+		return "UTF-8-BOM", string(body[len(bomUTF8):]), nil
+	case bytes.HasPrefix(body, bomUTF16LE):
+		from, body = "UTF-16LE", body[len(bomUTF16LE):]
+	case bytes.HasPrefix(body, bomUTF16BE):
+		from, body = "UTF-16BE", body[len(bomUTF16BE):]
+	}
+
+	charset := from
+	if charset == "" {
+		if utf8.Valid(body) {
+			return "UTF-8", string(body), nil
+		}
+
+		var ok bool
+		if charset, ok = String(string(body)).Charset(); !ok {
+			return "", "", errors.New("snake: cannot detect charset")
+		}
+	}
+
+	enc, err := textEncoding(charset)
+	if err != nil {
+		return charset, "", err
+	}
+
+	text, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return charset, "", err
+	}
+
+	if back, err := enc.NewEncoder().Bytes(text); err != nil || !bytes.Equal(back, body) {
+		return charset, "", fmt.Errorf("snake: content is not valid %s", charset)
+	}
+	return charset, string(text), nil
+}
+
+// isBinary 开头包含 NUL 字节且没有 UTF-16 BOM 的内容视为二进制
+func isBinary(body []byte) bool {
+	if bytes.HasPrefix(body, bomUTF16LE) || bytes.HasPrefix(body, bomUTF16BE) {
+		return false
+	}
+	if len(body) > binarySniff {
+		body = body[:binarySniff]
+	}
+	return bytes.IndexByte(body, 0) >= 0
+}
@@ -0,0 +1,208 @@
+package snake
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// transcodeText 足够长、能可靠检测编码的中文文本
+var transcodeText = strings.Repeat("这是一段用于检测编码的中文文本，包含常用的汉字。\n", 10)
+
+// transcodeTree 生成转换编码测试使用的目录
+func transcodeTree(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "src")
+	os.MkdirAll(filepath.Join(dir, "vendor"), 0755)
+
+	gbk, _ := encodeText(transcodeText, "GBK")
+	utf16, _ := encodeText(transcodeText, "UTF-16LE")
+	os.WriteFile(filepath.Join(dir, "gbk.txt"), gbk, 0600)
+	os.WriteFile(filepath.Join(dir, "utf8.txt"), []byte(transcodeText), 0644)
+	os.WriteFile(filepath.Join(dir, "utf16.txt"), append([]byte{0xff, 0xfe}, utf16...), 0644)
+	os.WriteFile(filepath.Join(dir, "bom.txt"), append([]byte{0xef, 0xbb, 0xbf}, transcodeText...), 0644)
+	os.WriteFile(filepath.Join(dir, "image.bin"), []byte("PNG\x00\x01\x02"), 0644)
+	os.WriteFile(filepath.Join(dir, "vendor", "lib.txt"), gbk, 0644)
+	return dir
+}
+
+// transcodePaths 返回报告中的路径
+func transcodePaths(files []TranscodeFile) []string {
+	var res []string
+	for _, v := range files {
+		res = append(res, v.Path)
+	}
+	return res
+}
+
+func TestTranscode(t *testing.T) {
+	dir := transcodeTree(t)
+	vendor, _ := os.ReadFile(filepath.Join(dir, "vendor", "lib.txt"))
+
+	report, err := FS(dir).Transcode(TranscodeOptions{Exclude: []string{"vendor"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// GBK 的内容检测为其超集 GB18030
+	want := []TranscodeFile{{Path: "bom.txt", Charset: "UTF-8-BOM"}, {Path: "gbk.txt", Charset: "GB18030"}, {Path: "utf16.txt", Charset: "UTF-16LE"}}
+	if !reflect.DeepEqual(report.Converted, want) {
+		t.Fatalf("Converted = %+v, want %+v", report.Converted, want)
+	}
+	if !reflect.DeepEqual(report.Unchanged, []string{"utf8.txt"}) || !reflect.DeepEqual(report.Binary, []string{"image.bin"}) {
+		t.Fatalf("Unchanged = %v, Binary = %v", report.Unchanged, report.Binary)
+	}
+
+	for _, name := range []string{"bom.txt", "gbk.txt", "utf16.txt", "utf8.txt"} {
+		if b, _ := os.ReadFile(filepath.Join(dir, name)); string(b) != transcodeText {
+			t.Fatalf("%s not converted to UTF-8", name)
+		}
+	}
+	// 保留原文件的权限，排除的目录保持原样
+	if info, _ := os.Stat(filepath.Join(dir, "gbk.txt")); info.Mode().Perm() != 0600 {
+		t.Fatalf("gbk.txt mode = %v", info.Mode())
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "vendor", "lib.txt")); !bytes.Equal(b, vendor) {
+		t.Fatal("excluded file converted")
+	}
+}
+
+func TestTranscodeOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		opt       TranscodeOptions
+		converted []string
+		failed    []string
+	}{
+		{"include", TranscodeOptions{Include: []string{"gbk.*"}}, []string{"gbk.txt"}, nil},
+		{"to gbk", TranscodeOptions{To: "GBK", Include: []string{"utf8.txt", "bom.txt"}}, []string{"bom.txt", "utf8.txt"}, nil},
+		{"to utf-16", TranscodeOptions{To: "UTF-16", Include: []string{"utf8.txt"}}, []string{"utf8.txt"}, nil},
+		// 指定的源编码与内容不符时保持原样
+		{"wrong from", TranscodeOptions{From: "Shift_JIS", Include: []string{"gbk.txt"}}, nil, []string{"gbk.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := transcodeTree(t)
+			before, _ := os.ReadFile(filepath.Join(dir, "gbk.txt"))
+
+			report, err := FS(dir).Transcode(tt.opt)
+			if (err != nil) != (len(tt.failed) > 0) {
+				t.Fatalf("err = %v", err)
+			}
+			if got := transcodePaths(report.Converted); !reflect.DeepEqual(got, tt.converted) {
+				t.Fatalf("Converted = %v, want %v", got, tt.converted)
+			}
+			if got := transcodePaths(report.Failed); !reflect.DeepEqual(got, tt.failed) {
+				t.Fatalf("Failed = %v, want %v", got, tt.failed)
+			}
+			for _, v := range report.Failed {
+				if v.Err == nil {
+					t.Fatalf("%s failed without a reason", v.Path)
+				}
+			}
+			if tt.failed != nil {
+				if b, _ := os.ReadFile(filepath.Join(dir, "gbk.txt")); !bytes.Equal(b, before) {
+					t.Fatal("failed file changed")
+				}
+			}
+
+			// 转换结果能按目标编码读回
+			for _, name := range tt.converted {
+				f, _ := FS(dir, name).Open()
+				text, err := f.Text(tt.opt.To)
+				f.Close()
+				if err != nil || text != transcodeText {
+					t.Fatalf("%s: read back %q, %v", name, text, err)
+				}
+			}
+		})
+	}
+}
+
+// TestTranscodeUnrepresentable 不能用目标编码表示的文件记入 Failed 并保持原样
+func TestTranscodeUnrepresentable(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "emoji.txt"), []byte("表情 😀\n"), 0644)
+
+	report, err := FS(dir).Transcode(TranscodeOptions{To: "GBK"})
+	if err == nil || len(report.Failed) != 1 || report.Failed[0].Path != "emoji.txt" {
+		t.Fatalf("report = %+v, %v", report, err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "emoji.txt")); string(b) != "表情 😀\n" {
+		t.Fatalf("file changed to %q", b)
+	}
+
+	if _, err := FS(dir).Transcode(TranscodeOptions{To: "unknown"}); err == nil {
+		t.Fatal("unknown charset accepted")
+	}
+}
+
+// TestTranscodeOutput 镜像目录中写入转换结果并原样复制其它文件，源目录保持不变
+func TestTranscodeOutput(t *testing.T) {
+	dir := transcodeTree(t)
+	gbk, _ := os.ReadFile(filepath.Join(dir, "gbk.txt"))
+	output := filepath.Join(dir, "out")
+
+	report, err := FS(dir).Transcode(TranscodeOptions{Exclude: []string{"vendor"}, Output: output})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Converted) != 3 {
+		t.Fatalf("Converted = %+v", report.Converted)
+	}
+
+	if b, _ := os.ReadFile(filepath.Join(dir, "gbk.txt")); !bytes.Equal(b, gbk) {
+		t.Fatal("source file changed")
+	}
+	tests := map[string][]byte{
+		"gbk.txt":        []byte(transcodeText),
+		"utf8.txt":       []byte(transcodeText),
+		"image.bin":      []byte("PNG\x00\x01\x02"),
+		"vendor/lib.txt": gbk,
+	}
+	for name, want := range tests {
+		if b, _ := os.ReadFile(filepath.Join(output, name)); !bytes.Equal(b, want) {
+			t.Errorf("%s = %q", name, b)
+		}
+	}
+	if FS(output, "out").Exist() {
+		t.Fatal("output directory copied into itself")
+	}
+}
+
+// TestTranscodePlan DryRun 时只记录计划，不写入文件
+func TestTranscodePlan(t *testing.T) {
+	dir := transcodeTree(t)
+	gbk, _ := os.ReadFile(filepath.Join(dir, "gbk.txt"))
+
+	plan := DryRun()
+	report, err := plan.FS(dir).Transcode()
+	if err != nil || len(report.Converted) != 4 {
+		t.Fatalf("report = %+v, %v", report, err)
+	}
+	if len(plan.Steps) != len(report.Converted) {
+		t.Fatalf("recorded %d steps, want %d", len(plan.Steps), len(report.Converted))
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "gbk.txt")); !bytes.Equal(b, gbk) {
+		t.Fatal("dry run wrote to disk")
+	}
+
+	if err := plan.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "gbk.txt")); string(b) != transcodeText {
+		t.Fatal("plan not executed")
+	}
+}
+
+func TestTranscodeNotDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(path, []byte("a"), 0644)
+	if _, err := FS(path).Transcode(); err == nil {
+		t.Fatal("file accepted as directory")
+	}
+}
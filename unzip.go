@@ -0,0 +1,192 @@
+package snake
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// UnzipOptions 解压选项
+// 数值为0时使用 DefaultUnzipOptions 中的默认值，小于0时不限制。
+type UnzipOptions struct {
+	MaxSize      int64  // 解压后的总大小上限
+	MaxFiles     int    // 条目数量上限
+	MaxRatio     int64  // 单个条目的压缩比上限
+	SkipSymlinks bool   // 跳过符号链接条目，默认只允许指向解压目录内的链接
+	Password     string // 加密条目的密码，支持 WinZip AES 与传统 ZipCrypto
+	Charset      string // 未标记 UTF-8 的条目名称的编码，如 GBK、Shift_JIS，为空时自动检测
+}
+
+// DefaultUnzipOptions 默认解压限制
+var DefaultUnzipOptions = UnzipOptions{
+	MaxSize:  1 << 30,
+	MaxFiles: 10000,
+	MaxRatio: 100,
+}
+
+// UnzipRule 解压安全规则
+type UnzipRule string
+
+const (
+	RuleAbsolutePath  UnzipRule = "absolute path"     // 条目使用绝对路径
+	RulePathTraversal UnzipRule = "path traversal"    // 条目通过 .. 或符号链接越出解压目录
+	RuleMaxSize       UnzipRule = "max size"          // 解压后总大小超过上限
+	RuleMaxFiles      UnzipRule = "max files"         // 条目数量超过上限
+	RuleMaxRatio      UnzipRule = "compression ratio" // 压缩比超过上限
+	RuleSymlink       UnzipRule = "symlink"           // 符号链接指向解压目录外
+	RuleExists        UnzipRule = "file exists"       // 目标已存在，见 OverwriteError
+)
+
+// UnzipError 解压时违反安全规则的条目
+type UnzipError struct {
+	Entry string    // 条目名称
+	Rule  UnzipRule // 违反的规则
+}
+
+func (e *UnzipError) Error() string {
+	return fmt.Sprintf("snake: unzip entry %q violates rule: %s", e.Entry, e.Rule)
+}
+
+const (
+	maxSymlinkTarget = 4096    // 符号链接目标的最大长度
+	minRatioSize     = 1 << 20 // 小于该大小的条目不检查压缩比
+)
+
+// ---------------------------------------
+// 处理 :
+
+// Unzip 安全解压zip文件到同名目录
+// 解压前校验所有条目的路径与声明大小，解压时按实际写入的字节数再次校验大小与压缩比，
+// 解压其它格式或指定目标目录请使用 Extract。
+// 违反规则时返回 *UnzipError。在事务中与 Extract 相同，解压目录已存在时返回错误。
+// 例子：
+// snake.FS("upload.zip").Unzip(snake.UnzipOptions{MaxSize: 100 << 20})
+func (sk *snakeFileSystem) Unzip(opts ...UnzipOptions) (string, error) {
+	if _, ok := sk.pathdst(); !ok {
+		return "", sk.err
+	}
+
+	base := FS(sk.Dir()).Add(String(sk.Base()).Remove(sk.Ext()).Get())
+
+	if _, err := sk.resolve(base.Get()); err != nil {
+		sk.err = err
+		return base.Get(), err
+	}
+
+	if sk.plan != nil {
+		return sk.plan.unzip(sk.Path, base.Get(), opts...)
+	}
+
+	// 以解压目录为根，阻止通过已解压的符号链接写到目录外
+	opt := ExtractOptions{UnzipOptions: unzipOptions(opts...)}
+	if sk.tx != nil {
+		return base.Get(), sk.tx.extract(base.Get(), func(dir string) error { return extractTo(sk.Path, dir, formatZip, opt) })
+	}
+
+	if !base.MkDir() {
+		return base.Get(), fmt.Errorf("snake: create directory %s failed", base.Get())
+	}
+	return base.Get(), extractTo(sk.Path, base.Get(), formatZip, opt)
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// unzipOptions 合并默认解压选项
+func unzipOptions(opts ...UnzipOptions) UnzipOptions {
+	opt := DefaultUnzipOptions
+	if len(opts) > 0 {
+		if opts[0].MaxSize != 0 {
+			opt.MaxSize = opts[0].MaxSize
+		}
+		if opts[0].MaxFiles != 0 {
+			opt.MaxFiles = opts[0].MaxFiles
+		}
+		if opts[0].MaxRatio != 0 {
+			opt.MaxRatio = opts[0].MaxRatio
+		}
+		opt.SkipSymlinks = opts[0].SkipSymlinks
+		opt.Password = opts[0].Password
+		opt.Charset = opts[0].Charset
+	}
+	return opt
+}
+
+// check 校验条目名称、数量与声明的大小
+func (opt UnzipOptions) check(files []*zip.File) error {
+	var total uint64
+	for i, file := range files {
+		if _, err := entryName(file.Name); err != nil {
+			return err
+		}
+		if opt.MaxFiles > 0 && i >= opt.MaxFiles {
+			return &UnzipError{Entry: file.Name, Rule: RuleMaxFiles}
+		}
+		total += file.UncompressedSize64
+		if opt.MaxSize > 0 && total > uint64(opt.MaxSize) {
+			return &UnzipError{Entry: file.Name, Rule: RuleMaxSize}
+		}
+		if opt.exceedRatio(file.UncompressedSize64, file.CompressedSize64) {
+			return &UnzipError{Entry: file.Name, Rule: RuleMaxRatio}
+		}
+	}
+	return nil
+}
+
+// zip 解压 zip 归档，写入任何文件前先校验所有条目
+func (e *extractor) zip(src string) error {
+	z, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer z.Close()
+
+	// 在校验前转换名称，GBK、Shift_JIS 的双字节中可能包含 \
+	if err := decodeZipNames(z.File, e.opt.Charset); err != nil {
+		return err
+	}
+
+	if err := e.opt.check(z.File); err != nil {
+		return err
+	}
+
+	for _, file := range z.File {
+		entry := archiveEntry{name: file.Name, mode: file.Mode(), modTime: file.Modified}
+		open := func() (io.ReadCloser, error) { return openZipEntry(file, e.opt.Password) }
+		if err := e.entry(entry, open, int64(file.CompressedSize64)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exceedRatio 判断压缩比是否超过上限
+func (opt UnzipOptions) exceedRatio(size, compressed uint64) bool {
+	if opt.MaxRatio <= 0 || size <= minRatioSize {
+		return false
+	}
+	if compressed == 0 {
+		return true
+	}
+	return size/compressed > uint64(opt.MaxRatio)
+}
+
+// entryName 校验并清理条目名称，拒绝绝对路径与越出解压目录的路径
+func entryName(name string) (string, error) {
+	clean := String(name).Replace(`\`, "/", true).Get()
+
+	if strings.HasPrefix(clean, "/") || filepath.VolumeName(clean) != "" || (len(clean) > 1 && clean[1] == ':') {
+		return "", &UnzipError{Entry: name, Rule: RuleAbsolutePath}
+	}
+
+	clean = path.Clean(clean)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", &UnzipError{Entry: name, Rule: RulePathTraversal}
+	}
+
+	return clean, nil
+}
@@ -0,0 +1,62 @@
+package snake
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnzipLimits(t *testing.T) {
+	// 随机内容不可压缩，只触发大小与数量限制
+	rnd := rand.New(rand.NewSource(1))
+	files := func(n, size int) []testEntry {
+		var res []testEntry
+		for i := 0; i < n; i++ {
+			body := make([]byte, size)
+			rnd.Read(body)
+			res = append(res, testEntry{name: string(rune('a'+i)) + ".bin", body: string(body)})
+		}
+		return res
+	}
+	zeros := []testEntry{{name: "zeros", body: string(make([]byte, 4<<20))}}
+
+	tests := []struct {
+		name    string
+		entries []testEntry
+		opt     UnzipOptions
+		rule    UnzipRule // 为空时应解压成功
+	}{
+		{name: "within limits", entries: files(3, 100), opt: UnzipOptions{MaxFiles: 3, MaxSize: 300}},
+		{name: "max files", entries: files(3, 1), opt: UnzipOptions{MaxFiles: 2}, rule: RuleMaxFiles},
+		{name: "max files unlimited", entries: files(3, 1), opt: UnzipOptions{MaxFiles: -1}},
+		{name: "max size", entries: files(2, 600<<10), opt: UnzipOptions{MaxSize: 1 << 20}, rule: RuleMaxSize},
+		{name: "max size unlimited", entries: files(2, 600<<10), opt: UnzipOptions{MaxSize: -1, MaxRatio: -1}},
+		{name: "max ratio", entries: zeros, opt: UnzipOptions{MaxRatio: 10}, rule: RuleMaxRatio},
+		{name: "max ratio default", entries: zeros, rule: RuleMaxRatio},
+		{name: "max ratio unlimited", entries: zeros, opt: UnzipOptions{MaxRatio: -1}},
+		{name: "small entry ignores ratio", entries: []testEntry{{name: "z", body: string(make([]byte, 512<<10))}}, opt: UnzipOptions{MaxRatio: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"/zip", func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), "a.zip")
+			writeZip(t, src, tt.entries)
+
+			_, err := FS(src).Unzip(tt.opt)
+			if rule := unzipRule(err); rule != tt.rule || (tt.rule == "" && err != nil) {
+				t.Fatalf("Unzip error = %v, want rule %q", err, tt.rule)
+			}
+		})
+
+		t.Run(tt.name+"/tar.gz", func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "a.tar.gz")
+			writeTar(t, src, true, tt.entries)
+
+			err := FS(src).Extract(filepath.Join(dir, "out"), ExtractOptions{UnzipOptions: tt.opt})
+			if rule := unzipRule(err); rule != tt.rule || (tt.rule == "" && err != nil) {
+				t.Fatalf("Extract error = %v, want rule %q", err, tt.rule)
+			}
+		})
+	}
+}
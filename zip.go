@@ -3,36 +3,267 @@ package snake
 import (
 	"archive/zip"
 	"bytes"
+	"compress/flate"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"time"
 )
 
 type Ziplib struct {
+	// Deprecated: 条目直接写入目标，不再缓存在内存中，该字段始终为空。
 	Buffer   *bytes.Buffer
 	FS       *zip.Writer
 	FileName string
+	Skipped  []SkippedEntry // 被排除规则跳过的条目
+	file     *pendingFile   // Zip() 写入的临时文件，Close 成功时重命名为 FileName
+	opt      ZipOptions
+	repro    *reproSpool // 可重现模式下暂存的条目
+	modTime  time.Time   // 可重现模式下的修改时间
+	sums     *Manifest   // 已写入文件条目的校验清单
+	err      error
 }
 
-func Zip(zipfile string) *Ziplib {
-	z := new(Ziplib)
-	z.Buffer = new(bytes.Buffer)
-	z.FS = zip.NewWriter(z.Buffer)
+// ZipOptions zip 归档选项
+type ZipOptions struct {
+	Level    int    // Deflate 压缩级别 1~9，0 时使用默认级别
+	Comment  string // 归档注释
+	Password string // 密码，设置后文件条目使用 WinZip AE-2 格式的 AES-256 加密
+	Charset  string // 条目名称的编码，如 GBK，供不支持 UTF-8 名称的旧工具读取，为空时使用 UTF-8
+
+	// 可重现模式：条目在 Close 时按名称排序写入，统一修改时间与权限，相同内容输出相同字节。
+	// 加密使用随机盐值，设置 Password 时输出不可重现。
+	Reproducible bool
+	ModTime      time.Time // 可重现模式下的修改时间，为零时使用 SOURCE_DATE_EPOCH，均未设置时为 1980-01-01
+
+	Ignore *IgnoreRules // 排除规则，为空时不排除任何条目，见 DefaultIgnore
+
+	Manifest HashAlgo // 设置后在 Close 时写入所有文件条目的校验清单，如 SHA256SUMS
+}
+
+// ZipEntryOptions zip 条目选项
+type ZipEntryOptions struct {
+	Modified time.Time   // 修改时间，为空时不记录
+	Mode     os.FileMode // 权限及类型，0 时文件为 0644、目录为 0755，包含 os.ModeSymlink 时内容为链接目标
+	Method   ZipMethod   // 压缩方式
+	Comment  string      // 条目注释
+}
+
+// ZipMethod zip 条目的压缩方式
+type ZipMethod int
+
+const (
+	MethodAuto    ZipMethod = iota // 根据 MimeTypes 判断，已压缩的格式使用 Store，其它使用 Deflate
+	MethodStore                    // 不压缩
+	MethodDeflate                  // Deflate 压缩
+)
+
+// storedMimes 已压缩的 MimeTypes 前缀，自动模式下不再压缩
+var storedMimes = []string{
+	"image/jpeg", "image/png", "image/gif", "image/webp", "image/avif", "image/heic",
+	"video/", "audio/mpeg", "audio/mp4", "audio/ogg", "audio/webm", "audio/x-aac", "audio/x-flac",
+	"font/woff", "application/zip", "application/gzip", "application/x-gzip", "application/x-bzip",
+	"application/x-xz", "application/x-7z-compressed", "application/x-rar-compressed", "application/zstd",
+	"application/java-archive", "application/vnd.android.package-archive", "application/epub+zip",
+	"application/vnd.openxmlformats-officedocument.", "application/vnd.oasis.opendocument.",
+}
+
+// Zip 新建 zip 归档文件，条目添加时即写入同目录下的临时文件
+// Close 成功时重命名为zipfile，出错时删除临时文件，zipfile 保持不变。
+func Zip(zipfile string, opts ...ZipOptions) *Ziplib {
+	f, err := createPending(zipfile)
+	if err != nil {
+		return &Ziplib{FileName: zipfile, err: err}
+	}
+	z := ZipWriter(f, opts...)
 	z.FileName = zipfile
+	z.file = f
 	return z
 }
 
-func (z *Ziplib) Add(path string, body []byte) bool {
-	if !String(path).Find(".DS_Store", true) && !String(path).Find("__MACOSX", true) {
-		if file, err := z.FS.Create(path); err == nil {
-			_, err := file.Write(body)
-			return err == nil
+// ZipWriter 新建写入w的 zip 归档，条目添加时即写入w，不在内存中缓存
+func ZipWriter(w io.Writer, opts ...ZipOptions) *Ziplib {
+	z := new(Ziplib)
+	z.FS = zip.NewWriter(w)
+
+	if len(opts) > 0 {
+		z.opt = opts[0]
+	}
+	if z.opt.Level != 0 {
+		z.FS.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(w, z.level())
+		})
+	}
+	if z.opt.Comment != "" {
+		z.err = z.FS.SetComment(z.opt.Comment)
+	}
+	if z.opt.Reproducible {
+		z.repro, z.modTime = &reproSpool{}, reproTime(z.opt.ModTime)
+	}
+	if z.opt.Manifest != "" {
+		if _, err := newHash(z.opt.Manifest); err != nil {
+			z.err = err
+		}
+		z.sums = &Manifest{Algo: z.opt.Manifest}
+	}
+	return z
+}
+
+func (z *Ziplib) Add(path string, body []byte, opts ...ZipEntryOptions) bool {
+	ok, _ := z.AddReader(path, bytes.NewReader(body), opts...)
+	return ok
+}
+
+// AddReader 从r读取条目内容，以 / 结尾的路径为目录
+// 条目被排除规则跳过时返回false与nil错误，并记录到 Skipped。
+// 例子：
+// z.AddReader("bin/run.sh", f, snake.ZipEntryOptions{Modified: stat.ModTime(), Mode: stat.Mode()})
+func (z *Ziplib) AddReader(path string, r io.Reader, opts ...ZipEntryOptions) (bool, error) {
+	if z.err != nil {
+		return false, z.err
+	}
+
+	if rule, ok := z.opt.Ignore.Match(path, strings.HasSuffix(path, "/")); ok {
+		z.Skipped = append(z.Skipped, SkippedEntry{Path: path, Rule: rule})
+		return false, nil
+	}
+
+	var opt ZipEntryOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	header := zipHeader(path, opt)
+
+	var h hash.Hash
+	name := header.Name
+	if z.sums != nil && !strings.HasSuffix(name, "/") && opt.Mode&os.ModeType == 0 {
+		r, h = z.sums.tee(r)
+	}
+
+	if z.opt.Charset != "" {
+		if err := encodeZipName(header, z.opt.Charset); err != nil {
+			return false, err
 		}
 	}
-	return false
+
+	var err error
+	if z.repro != nil {
+		reproZipHeader(header, z.modTime)
+		_, err = z.repro.add(header.Name, r, func(body io.Reader) error { return z.write(header, body) })
+	} else {
+		err = z.write(header, r)
+	}
+
+	if err == nil && h != nil {
+		z.sums.record(name, h)
+	}
+	return err == nil, err
 }
 
 func (z *Ziplib) Close() error {
-	err := z.FS.Close()
-	if err == nil {
-		FS(z.FileName).ByteWriter(z.Buffer.Bytes())
+	if z.err != nil {
+		if z.repro != nil {
+			z.repro.close()
+		}
+		if z.file != nil {
+			z.file.commit(z.err)
+			z.file = nil
+		}
+		return z.err
+	}
+
+	var err error
+	if z.sums != nil {
+		err = z.addManifest()
+	}
+	if z.repro != nil {
+		if e := z.repro.flush(); err == nil {
+			err = e
+		}
+		z.repro.close()
+	}
+	if e := z.FS.Close(); err == nil {
+		err = e
 	}
+	if z.file != nil {
+		err = z.file.commit(err)
+		z.file = nil
+	}
+	return err
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// write 写入条目头及内容，设置密码时加密
+func (z *Ziplib) write(header *zip.FileHeader, r io.Reader) error {
+	if z.opt.Password != "" && !strings.HasSuffix(header.Name, "/") {
+		return z.addEncrypted(header, r)
+	}
+
+	file, err := z.FS.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, r)
 	return err
 }
+
+// addManifest 写入校验清单条目
+func (z *Ziplib) addManifest() error {
+	sums := z.sums.sorted()
+	z.sums = nil
+	_, err := z.AddReader(ManifestName(sums.Algo), strings.NewReader(sums.String()), ZipEntryOptions{Modified: time.Now()})
+	return err
+}
+
+// level 返回 Deflate 压缩级别
+func (z *Ziplib) level() int {
+	if z.opt.Level == 0 {
+		return flate.DefaultCompression
+	}
+	return z.opt.Level
+}
+
+// zipHeader 根据条目选项生成文件头
+func zipHeader(path string, opt ZipEntryOptions) *zip.FileHeader {
+	header := &zip.FileHeader{Name: path, Comment: opt.Comment, Modified: opt.Modified}
+
+	isDir := strings.HasSuffix(path, "/")
+	mode := opt.Mode
+	if mode == 0 {
+		mode = 0644
+		if isDir {
+			mode = os.ModeDir | 0755
+		}
+	} else if isDir {
+		mode |= os.ModeDir
+	}
+	// SetMode 记录 Unix 权限，解压后保留可执行位
+	header.SetMode(mode)
+
+	switch {
+	case isDir || opt.Method == MethodStore:
+		header.Method = zip.Store
+	case opt.Method == MethodDeflate:
+		header.Method = zip.Deflate
+	default:
+		header.Method = zipMethod(path)
+	}
+
+	return header
+}
+
+// zipMethod 根据 MimeTypes 选择压缩方式，已压缩的格式不再压缩
+func zipMethod(path string) uint16 {
+	mime := FS(path).MimeTypes()
+	for _, v := range storedMimes {
+		if mime != "" && strings.HasPrefix(mime, v) {
+			return zip.Store
+		}
+	}
+	return zip.Deflate
+}
@@ -0,0 +1,145 @@
+package snake
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestZipWriterStreams(t *testing.T) {
+	body := make([]byte, 1<<20)
+	rand.Read(body)
+
+	var buf bytes.Buffer
+	z := ZipWriter(&buf)
+	if ok, err := z.AddReader("big.bin", bytes.NewReader(body), ZipEntryOptions{Method: MethodStore}); !ok {
+		t.Fatal(err)
+	}
+	// 条目添加时即写入w，不等到 Close
+	if buf.Len() < len(body) {
+		t.Fatalf("%d bytes written before Close, want at least %d", buf.Len(), len(body))
+	}
+	if !z.Add("dir/", nil) || !z.Add("dir/small.txt", []byte("small")) {
+		t.Fatal("Add failed")
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if z.Buffer != nil {
+		t.Fatal("deprecated Buffer is set")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"big.bin": string(body), "dir/": "", "dir/small.txt": "small"}
+	if len(zr.File) != len(want) {
+		t.Fatalf("%d entries, want %d", len(zr.File), len(want))
+	}
+	for _, file := range zr.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(b) != want[file.Name] {
+			t.Fatalf("%s: content mismatch", file.Name)
+		}
+	}
+}
+
+func TestZipEntryOptions(t *testing.T) {
+	modified := time.Date(2024, 5, 6, 7, 8, 10, 0, time.UTC)
+	tests := []struct {
+		name   string
+		opt    ZipEntryOptions
+		method uint16
+		mode   os.FileMode
+	}{
+		{name: "auto text", method: zip.Deflate, mode: 0644},
+		{name: "auto image.png", method: zip.Store, mode: 0644},
+		{name: "store.txt", opt: ZipEntryOptions{Method: MethodStore}, method: zip.Store, mode: 0644},
+		{name: "deflate.png", opt: ZipEntryOptions{Method: MethodDeflate}, method: zip.Deflate, mode: 0644},
+		{name: "run.sh", opt: ZipEntryOptions{Mode: 0755, Modified: modified, Comment: "script"}, method: zip.Deflate, mode: 0755},
+		{name: "link", opt: ZipEntryOptions{Mode: os.ModeSymlink | 0777}, method: zip.Deflate, mode: os.ModeSymlink | 0777},
+		{name: "dir/", method: zip.Store, mode: os.ModeDir | 0755},
+	}
+
+	var buf bytes.Buffer
+	z := ZipWriter(&buf, ZipOptions{Level: 9, Comment: "archive comment"})
+	for _, tt := range tests {
+		body := []byte(strings.Repeat("content ", 100))
+		if strings.HasSuffix(tt.name, "/") {
+			body = nil
+		}
+		if !z.Add(tt.name, body, tt.opt) {
+			t.Fatalf("Add %s failed", tt.name)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zr.Comment != "archive comment" {
+		t.Fatalf("archive comment = %q", zr.Comment)
+	}
+	for i, tt := range tests {
+		file := zr.File[i]
+		if file.Name != tt.name || file.Method != tt.method || file.Mode() != tt.mode {
+			t.Errorf("%s: method %d, mode %v, want %d %v", file.Name, file.Method, file.Mode(), tt.method, tt.mode)
+		}
+		if file.Comment != tt.opt.Comment {
+			t.Errorf("%s: comment %q", file.Name, file.Comment)
+		}
+		if !tt.opt.Modified.IsZero() && !file.Modified.Equal(tt.opt.Modified) {
+			t.Errorf("%s: modified %v, want %v", file.Name, file.Modified, tt.opt.Modified)
+		}
+	}
+}
+
+// TestArchiveZipMetadata 目录归档为 zip 时保留修改时间、权限与符号链接
+func TestArchiveZipMetadata(t *testing.T) {
+	dir := archiveTree(t)
+	modified := time.Date(2024, 5, 6, 7, 8, 10, 0, time.UTC)
+	os.Chtimes(filepath.Join(dir, "a.txt"), modified, modified)
+
+	target := filepath.Join(filepath.Dir(dir), "out.zip")
+	if err := FS(dir).Archive(target); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	files := map[string]*zip.File{}
+	for _, file := range zr.File {
+		files[file.Name] = file
+	}
+	if f := files["a.txt"]; f == nil || !f.Modified.Equal(modified) {
+		t.Fatalf("a.txt modified = %v, want %v", f.Modified, modified)
+	}
+	if f := files["run.sh"]; f == nil || f.Mode().Perm() != 0755 {
+		t.Fatal("run.sh lost its executable bit")
+	}
+	if f := files["link"]; f == nil || f.Mode()&os.ModeSymlink == 0 {
+		t.Fatal("link is not a symlink")
+	}
+	if f := files["empty/"]; f == nil || !f.Mode().IsDir() {
+		t.Fatal("empty directory missing")
+	}
+}
@@ -0,0 +1,484 @@
+package snake
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"unicode/utf8"
+)
+
+// ErrPasswordRequired 加密条目未提供密码
+var ErrPasswordRequired = errors.New("snake: zip entry is encrypted, password required")
+
+// ErrWrongPassword 密码错误
+var ErrWrongPassword = errors.New("snake: wrong zip password")
+
+// ErrZipAuth 加密条目校验失败，文件已损坏或密码错误
+var ErrZipAuth = errors.New("snake: zip entry authentication failed")
+
+const (
+	methodAES      = 99     // WinZip AES 加密条目的压缩方式标识
+	aesExtraID     = 0x9901 // WinZip AES 扩展字段
+	aesVersion2    = 2      // AE-2，不记录 CRC，依赖 HMAC 校验
+	aesStrength256 = 3
+	aesIterations  = 1000
+	aesMacLen      = 10
+	aesPwvLen      = 2
+	zipCryptoHead  = 12 // ZipCrypto 加密头长度
+
+	flagEncrypted      = 0x1
+	flagDataDescriptor = 0x8
+	flagUTF8           = 0x800
+)
+
+// ---------------------------------------
+// 写入 :
+
+// addEncrypted 以 WinZip AE-2 格式写入 AES-256 加密的条目
+func (z *Ziplib) addEncrypted(header *zip.FileHeader, r io.Reader) error {
+	method := header.Method
+
+	if !header.Modified.IsZero() {
+		header.SetModTime(header.Modified)
+	}
+	if !header.NonUTF8 && !isASCII(header.Name) && utf8.ValidString(header.Name) {
+		header.Flags |= flagUTF8
+	}
+	header.Method = methodAES
+	header.Flags |= flagEncrypted | flagDataDescriptor
+	header.CreatorVersion = header.CreatorVersion&0xff00 | 51
+	header.ReaderVersion = 51
+	header.CRC32 = 0
+	header.Extra = append(header.Extra, aesExtra(aesVersion2, aesStrength256, method)...)
+
+	w, err := z.FS.CreateRaw(header)
+	if err != nil {
+		return err
+	}
+
+	keyLen := aesKeyLen(aesStrength256)
+	salt := make([]byte, keyLen/2)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	keys := pbkdf2SHA1([]byte(z.opt.Password), salt, aesIterations, 2*keyLen+aesPwvLen)
+
+	if _, err := w.Write(salt); err != nil {
+		return err
+	}
+	if _, err := w.Write(keys[2*keyLen:]); err != nil {
+		return err
+	}
+
+	enc, err := newAESWriter(w, keys[:keyLen], keys[keyLen:2*keyLen])
+	if err != nil {
+		return err
+	}
+
+	var comp io.WriteCloser = nopWriteCloser{enc}
+	if method == zip.Deflate {
+		if comp, err = flate.NewWriter(enc, z.level()); err != nil {
+			return err
+		}
+	}
+
+	n, err := io.Copy(comp, r)
+	if err != nil {
+		return err
+	}
+	if err := comp.Close(); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(enc.mac.Sum(nil)[:aesMacLen]); err != nil {
+		return err
+	}
+
+	// CreateRaw 写入的数据描述符及中央目录使用以下大小
+	header.UncompressedSize64 = uint64(n)
+	header.CompressedSize64 = uint64(len(salt)+aesPwvLen+aesMacLen) + uint64(enc.n)
+	header.UncompressedSize = uint32Size(header.UncompressedSize64)
+	header.CompressedSize = uint32Size(header.CompressedSize64)
+	return nil
+}
+
+// aesWriter 加密数据并计算 HMAC-SHA1
+type aesWriter struct {
+	w   io.Writer
+	ctr *aesCTR
+	mac hash.Hash
+	n   int64
+}
+
+func newAESWriter(w io.Writer, key, macKey []byte) (*aesWriter, error) {
+	ctr, err := newAESCTR(key)
+	if err != nil {
+		return nil, err
+	}
+	return &aesWriter{w: w, ctr: ctr, mac: hmac.New(sha1.New, macKey)}, nil
+}
+
+func (a *aesWriter) Write(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+	a.ctr.XORKeyStream(buf, p)
+	a.mac.Write(buf)
+	n, err := a.w.Write(buf)
+	a.n += int64(n)
+	return n, err
+}
+
+// ---------------------------------------
+// 读取 :
+
+// openZipEntry 打开zip条目，支持 WinZip AES 与传统 ZipCrypto 加密
+func openZipEntry(file *zip.File, password string) (io.ReadCloser, error) {
+	if file.Flags&flagEncrypted == 0 {
+		return file.Open()
+	}
+
+	if password == "" {
+		return nil, fmt.Errorf("%w: %s", ErrPasswordRequired, file.Name)
+	}
+
+	raw, err := file.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	if file.Method == methodAES {
+		return openAES(file, raw, password)
+	}
+	return openZipCrypto(file, raw, password)
+}
+
+// openAES 解密 WinZip AES 条目
+func openAES(file *zip.File, raw io.Reader, password string) (io.ReadCloser, error) {
+	version, strength, method, ok := parseAESExtra(file.Extra)
+	keyLen := aesKeyLen(strength)
+	if !ok || keyLen == 0 {
+		return nil, fmt.Errorf("snake: zip entry %s: invalid AES extra field", file.Name)
+	}
+
+	saltLen := keyLen / 2
+	overhead := uint64(saltLen + aesPwvLen + aesMacLen)
+	if file.CompressedSize64 < overhead {
+		return nil, zip.ErrFormat
+	}
+
+	head := make([]byte, saltLen+aesPwvLen)
+	if _, err := io.ReadFull(raw, head); err != nil {
+		return nil, err
+	}
+
+	keys := pbkdf2SHA1([]byte(password), head[:saltLen], aesIterations, 2*keyLen+aesPwvLen)
+	if subtle.ConstantTimeCompare(keys[2*keyLen:], head[saltLen:]) != 1 {
+		return nil, fmt.Errorf("%w: %s", ErrWrongPassword, file.Name)
+	}
+
+	ctr, err := newAESCTR(keys[:keyLen])
+	if err != nil {
+		return nil, err
+	}
+
+	dec := &aesReader{
+		r:    io.LimitReader(raw, int64(file.CompressedSize64-overhead)),
+		raw:  raw,
+		ctr:  ctr,
+		mac:  hmac.New(sha1.New, keys[keyLen:2*keyLen]),
+		name: file.Name,
+	}
+
+	// AE-1 仍记录 CRC，AE-2 只依赖 HMAC
+	crc := uint32(0)
+	if version != aesVersion2 {
+		crc = file.CRC32
+	}
+	return decompressEntry(dec, method, crc, file.Name)
+}
+
+// aesReader 解密数据，读取结束时校验 HMAC-SHA1
+type aesReader struct {
+	r    io.Reader
+	raw  io.Reader
+	ctr  *aesCTR
+	mac  hash.Hash
+	name string
+	err  error // 校验结果，读取结束后重复返回
+}
+
+func (a *aesReader) Read(p []byte) (int, error) {
+	if a.err != nil {
+		return 0, a.err
+	}
+
+	n, err := a.r.Read(p)
+	a.mac.Write(p[:n])
+	a.ctr.XORKeyStream(p[:n], p[:n])
+
+	if err == io.EOF {
+		a.err = io.EOF
+		code := make([]byte, aesMacLen)
+		if _, e := io.ReadFull(a.raw, code); e != nil || !hmac.Equal(code, a.mac.Sum(nil)[:aesMacLen]) {
+			a.err = fmt.Errorf("%w: %s", ErrZipAuth, a.name)
+		}
+		return n, a.err
+	}
+	return n, err
+}
+
+// openZipCrypto 解密传统 PKWARE ZipCrypto 条目
+func openZipCrypto(file *zip.File, raw io.Reader, password string) (io.ReadCloser, error) {
+	if file.CompressedSize64 < zipCryptoHead {
+		return nil, zip.ErrFormat
+	}
+
+	keys := newZipCryptoKeys([]byte(password))
+
+	head := make([]byte, zipCryptoHead)
+	if _, err := io.ReadFull(raw, head); err != nil {
+		return nil, err
+	}
+	keys.decrypt(head)
+
+	// 加密头最后一字节为 CRC 高字节，使用数据描述符时为修改时间高字节
+	check := byte(file.CRC32 >> 24)
+	if file.Flags&flagDataDescriptor != 0 {
+		check = byte(file.ModifiedTime >> 8)
+	}
+	if head[zipCryptoHead-1] != check {
+		return nil, fmt.Errorf("%w: %s", ErrWrongPassword, file.Name)
+	}
+
+	dec := &zipCryptoReader{r: io.LimitReader(raw, int64(file.CompressedSize64-zipCryptoHead)), keys: keys}
+	return decompressEntry(dec, file.Method, file.CRC32, file.Name)
+}
+
+// zipCryptoKeys ZipCrypto 密钥状态
+type zipCryptoKeys [3]uint32
+
+func newZipCryptoKeys(password []byte) *zipCryptoKeys {
+	k := &zipCryptoKeys{0x12345678, 0x23456789, 0x34567890}
+	for _, b := range password {
+		k.update(b)
+	}
+	return k
+}
+
+func (k *zipCryptoKeys) update(b byte) {
+	k[0] = crc32Update(k[0], b)
+	k[1] = (k[1]+k[0]&0xff)*134775813 + 1
+	k[2] = crc32Update(k[2], byte(k[1]>>24))
+}
+
+func (k *zipCryptoKeys) decrypt(p []byte) {
+	for i, c := range p {
+		t := k[2] | 2
+		p[i] = c ^ byte((t*(t^1))>>8)
+		k.update(p[i])
+	}
+}
+
+type zipCryptoReader struct {
+	r    io.Reader
+	keys *zipCryptoKeys
+}
+
+func (z *zipCryptoReader) Read(p []byte) (int, error) {
+	n, err := z.r.Read(p)
+	z.keys.decrypt(p[:n])
+	return n, err
+}
+
+// ---------------------------------------
+// 辅助函数 :
+
+// decompressEntry 解压已解密的条目数据，crc不为0时校验 CRC32
+func decompressEntry(r io.Reader, method uint16, crc uint32, name string) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	switch method {
+	case zip.Store:
+		rc = io.NopCloser(r)
+	case zip.Deflate:
+		rc = flate.NewReader(r)
+	default:
+		return nil, fmt.Errorf("%w: %s", zip.ErrAlgorithm, name)
+	}
+
+	// 解压结束后读完剩余数据，确保触发 HMAC 校验
+	rc = &drainReader{ReadCloser: rc, r: r}
+
+	if crc == 0 {
+		return rc, nil
+	}
+	return &crcReader{ReadCloser: rc, crc: crc, hash: crc32.NewIEEE()}, nil
+}
+
+// drainReader 读取结束时读完底层数据
+type drainReader struct {
+	io.ReadCloser
+	r io.Reader
+}
+
+func (d *drainReader) Read(p []byte) (int, error) {
+	n, err := d.ReadCloser.Read(p)
+	if err == io.EOF {
+		if _, e := io.Copy(io.Discard, d.r); e != nil {
+			return n, e
+		}
+	}
+	return n, err
+}
+
+// crcReader 读取结束时校验 CRC32
+type crcReader struct {
+	io.ReadCloser
+	crc  uint32
+	hash hash.Hash32
+}
+
+func (c *crcReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.hash.Write(p[:n])
+	if err == io.EOF && c.hash.Sum32() != c.crc {
+		return n, zip.ErrChecksum
+	}
+	return n, err
+}
+
+// aesCTR WinZip AES 使用的小端序计数器 CTR 模式，计数器从1开始
+type aesCTR struct {
+	block   cipher.Block
+	counter [aes.BlockSize]byte
+	stream  [aes.BlockSize]byte
+	pos     int
+}
+
+func newAESCTR(key []byte) (*aesCTR, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &aesCTR{block: block, pos: aes.BlockSize}, nil
+}
+
+func (c *aesCTR) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if c.pos == aes.BlockSize {
+			for j := range c.counter {
+				c.counter[j]++
+				if c.counter[j] != 0 {
+					break
+				}
+			}
+			c.block.Encrypt(c.stream[:], c.counter[:])
+			c.pos = 0
+		}
+		dst[i] = src[i] ^ c.stream[c.pos]
+		c.pos++
+	}
+}
+
+// aesExtra 生成 WinZip AES 扩展字段
+func aesExtra(version uint16, strength byte, method uint16) []byte {
+	b := make([]byte, 11)
+	binary.LittleEndian.PutUint16(b[0:], aesExtraID)
+	binary.LittleEndian.PutUint16(b[2:], 7)
+	binary.LittleEndian.PutUint16(b[4:], version)
+	copy(b[6:], "AE")
+	b[8] = strength
+	binary.LittleEndian.PutUint16(b[9:], method)
+	return b
+}
+
+// parseAESExtra 解析 WinZip AES 扩展字段
+func parseAESExtra(extra []byte) (version uint16, strength byte, method uint16, ok bool) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			break
+		}
+		if id == aesExtraID && size >= 7 {
+			d := extra[4:]
+			return binary.LittleEndian.Uint16(d), d[4], binary.LittleEndian.Uint16(d[5:]), true
+		}
+		extra = extra[4+size:]
+	}
+	return 0, 0, 0, false
+}
+
+// aesKeyLen 根据加密强度返回密钥长度
+func aesKeyLen(strength byte) int {
+	switch strength {
+	case 1:
+		return 16
+	case 2:
+		return 24
+	case 3:
+		return 32
+	}
+	return 0
+}
+
+// pbkdf2SHA1 使用 HMAC-SHA1 的 PBKDF2 密钥派生
+func pbkdf2SHA1(password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(sha1.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	dk := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	var buf [4]byte
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf[:], uint32(block))
+		prf.Write(buf[:])
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+
+		for n := 2; n <= iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return dk[:keyLen]
+}
+
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[(crc^uint32(b))&0xff] ^ crc>>8
+}
+
+func uint32Size(size uint64) uint32 {
+	if size > 1<<32-1 {
+		return 1<<32 - 1
+	}
+	return uint32(size)
+}
+
+func isASCII(s string) bool {
+	return bytes.IndexFunc([]byte(s), func(r rune) bool { return r >= utf8.RuneSelf }) < 0
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,250 @@
+package snake
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// aesEntries AES 测试使用的条目
+var aesEntries = []struct {
+	name   string
+	body   string
+	method ZipMethod
+}{
+	{"store.txt", "stored content", MethodStore},
+	{"deflate.txt", strings.Repeat("compressible ", 10000), MethodDeflate},
+	{"empty.txt", "", MethodDeflate},
+	{"dir/nested.bin", "\x00\x01\x02\xff", MethodStore},
+}
+
+// writeAESZip 生成 AES-256 加密的 zip
+func writeAESZip(t *testing.T, path, password string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := ZipWriter(f, ZipOptions{Password: password})
+	for _, v := range aesEntries {
+		if ok, err := z.AddReader(v.name, strings.NewReader(v.body), ZipEntryOptions{Method: v.method}); !ok {
+			t.Fatalf("add %s: %v", v.name, err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+}
+
+func TestZipAESRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "secret.zip")
+	writeAESZip(t, src, "correct horse")
+
+	// 条目使用 WinZip AE-2、AES-256
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range zr.File {
+		version, strength, _, ok := parseAESExtra(file.Extra)
+		if file.Method != methodAES || !ok || version != aesVersion2 || strength != aesStrength256 {
+			t.Errorf("%s: method %d, extra %v %d %d", file.Name, file.Method, ok, version, strength)
+		}
+		if strings.Contains(string(readRaw(t, file)), "compressible") {
+			t.Errorf("%s: plaintext found in archive", file.Name)
+		}
+	}
+	zr.Close()
+
+	base, err := FS(src).Unzip(UnzipOptions{Password: "correct horse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range aesEntries {
+		if b, err := os.ReadFile(filepath.Join(base, v.name)); err != nil || string(b) != v.body {
+			t.Errorf("%s: content mismatch, %v", v.name, err)
+		}
+	}
+}
+
+func TestZipAESPassword(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "secret.zip")
+	writeAESZip(t, src, "correct horse")
+
+	tests := []struct {
+		name     string
+		password string
+		want     error
+	}{
+		{"missing", "", ErrPasswordRequired},
+		{"wrong", "battery staple", ErrWrongPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, err := FS(src).Unzip(UnzipOptions{Password: tt.password})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Unzip error = %v, want %v", err, tt.want)
+			}
+			if FS(base, aesEntries[0].name).Exist() {
+				t.Fatal("entry written with a bad password")
+			}
+		})
+	}
+}
+
+func TestZipAESTampered(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "secret.zip")
+	writeAESZip(t, src, "pw")
+
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file *zip.File
+	for _, v := range zr.File {
+		if v.Name == "store.txt" {
+			file = v
+		}
+	}
+	off, _ := file.DataOffset()
+	size := int64(file.CompressedSize64)
+	zr.Close()
+
+	saltLen := int64(aesKeyLen(aesStrength256) / 2)
+	tests := []struct {
+		name string
+		pos  int64 // 被修改的字节相对条目数据的位置
+	}{
+		{"ciphertext", saltLen + aesPwvLen},
+		{"last ciphertext byte", size - aesMacLen - 1},
+		{"hmac", size - aesMacLen},
+		{"last hmac byte", size - 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := os.ReadFile(src)
+			body[off+tt.pos] ^= 0x01
+			path := filepath.Join(t.TempDir(), "tampered.zip")
+			os.WriteFile(path, body, 0644)
+
+			zr, err := zip.OpenReader(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer zr.Close()
+			for _, v := range zr.File {
+				if v.Name != "store.txt" {
+					continue
+				}
+				rc, err := openZipEntry(v, "pw")
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, err = io.ReadAll(rc)
+				rc.Close()
+				if !errors.Is(err, ErrZipAuth) {
+					t.Fatalf("read error = %v, want ErrZipAuth", err)
+				}
+			}
+		})
+	}
+}
+
+func TestZipCryptoRead(t *testing.T) {
+	body := strings.Repeat("legacy ", 1000)
+	src := filepath.Join(t.TempDir(), "legacy.zip")
+	writeZipCrypto(t, src, "name.txt", body, "pw")
+
+	tests := []struct {
+		name     string
+		password string
+		want     error
+	}{
+		{"correct", "pw", nil},
+		{"wrong", "nope", ErrWrongPassword},
+		{"missing", "", ErrPasswordRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zr, err := zip.OpenReader(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer zr.Close()
+
+			rc, err := openZipEntry(zr.File[0], tt.password)
+			if err == nil {
+				var b []byte
+				b, err = io.ReadAll(rc)
+				rc.Close()
+				if err == nil && string(b) != body {
+					t.Fatal("content mismatch")
+				}
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// writeZipCrypto 生成传统 ZipCrypto 加密的 deflate 条目
+func writeZipCrypto(t *testing.T, path, name, body, password string) {
+	t.Helper()
+	var comp bytes.Buffer
+	fw, _ := flate.NewWriter(&comp, flate.BestCompression)
+	io.WriteString(fw, body)
+	fw.Close()
+
+	crc := crc32.ChecksumIEEE([]byte(body))
+	head := make([]byte, zipCryptoHead)
+	head[zipCryptoHead-1] = byte(crc >> 24)
+
+	keys := newZipCryptoKeys([]byte(password))
+	data := append(head, comp.Bytes()...)
+	for i, p := range data {
+		k := keys[2] | 2
+		data[i] = p ^ byte((k*(k^1))>>8)
+		keys.update(p)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               name,
+		Method:             zip.Deflate,
+		Flags:              flagEncrypted,
+		CRC32:              crc,
+		CompressedSize64:   uint64(len(data)),
+		UncompressedSize64: uint64(len(body)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	zw.Close()
+	os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// readRaw 读取条目未解密的原始数据
+func readRaw(t *testing.T, file *zip.File) []byte {
+	t.Helper()
+	r, err := file.OpenRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(r)
+	return b
+}
@@ -0,0 +1,90 @@
+package snake
+
+import (
+	"archive/zip"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+)
+
+// ---------------------------------------
+// 辅助函数 :
+
+// decodeZipNames 将未标记 UTF-8 的条目名称与注释转换为 UTF-8
+// charset 为空时根据所有非 UTF-8 名称检测编码，名称均为有效 UTF-8 时不转换。
+func decodeZipNames(files []*zip.File, charset string) error {
+	auto := charset == ""
+	if auto {
+		charset = zipCharset(files)
+		if charset == "" || charset == "UTF-8" {
+			return nil
+		}
+	}
+
+	// 检测到的编码不支持时不转换
+	enc, err := zipEncoding(charset)
+	if err != nil {
+		if auto {
+			return nil
+		}
+		return err
+	}
+
+	decoder := enc.NewDecoder()
+	for _, file := range files {
+		// 设置了 UTF-8 标记的条目不转换，自动检测时有效的 UTF-8 名称也不转换
+		if file.Flags&flagUTF8 != 0 || (auto && utf8.ValidString(file.Name)) {
+			continue
+		}
+		if name, err := decoder.String(file.Name); err == nil {
+			file.Name = name
+		}
+		if comment, err := decoder.String(file.Comment); err == nil {
+			file.Comment = comment
+		}
+		file.NonUTF8 = false
+	}
+	return nil
+}
+
+// zipCharset 根据未标记 UTF-8 且无效的名称检测编码，无需转换时返回空
+// 单个名称太短，合并后检测更准确。
+func zipCharset(files []*zip.File) string {
+	var names []string
+	for _, file := range files {
+		if file.NonUTF8 && !utf8.ValidString(file.Name) {
+			names = append(names, file.Name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+
+	charset, _ := String(strings.Join(names, "\n")).Charset()
+	return charset
+}
+
+// encodeZipName 将条目名称与注释转换为charset编码，并标记为非 UTF-8
+func encodeZipName(header *zip.FileHeader, charset string) error {
+	enc, err := zipEncoding(charset)
+	if err != nil {
+		return err
+	}
+
+	encoder := enc.NewEncoder()
+	if header.Name, err = encoder.String(header.Name); err != nil {
+		return fmt.Errorf("snake: encode zip entry name to %s: %w", charset, err)
+	}
+	if header.Comment, err = encoder.String(header.Comment); err != nil {
+		return fmt.Errorf("snake: encode zip entry comment to %s: %w", charset, err)
+	}
+	header.NonUTF8 = true
+	return nil
+}
+
+// zipEncoding 根据编码名称返回编码
+func zipEncoding(charset string) (encoding.Encoding, error) {
+	return textEncoding(charset)
+}
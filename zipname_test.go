@@ -0,0 +1,159 @@
+package snake
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// legacyZip 生成条目名称为charset编码且未标记 UTF-8 的 zip
+func legacyZip(t *testing.T, charset string, names ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	z := ZipWriter(&buf, ZipOptions{Charset: charset})
+	for _, name := range names {
+		if !z.Add(name, []byte(name)) {
+			t.Fatalf("Add %s failed", name)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestZipNameEncode(t *testing.T) {
+	tests := []struct {
+		charset string
+		name    string
+		enc     encoding.Encoding
+	}{
+		{"GBK", "中文目录/说明.txt", simplifiedchinese.GBK},
+		{"gb18030", "报表.csv", simplifiedchinese.GB18030},
+		{"Shift_JIS", "日本語/ファイル.txt", japanese.ShiftJIS},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.charset, func(t *testing.T) {
+			want, _ := tt.enc.NewEncoder().String(tt.name)
+			b := legacyZip(t, tt.charset, tt.name)
+			zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			file := zr.File[0]
+			if file.Name != want || file.Flags&flagUTF8 != 0 {
+				t.Fatalf("name = %q, flags %#x, want %q without UTF-8 flag", file.Name, file.Flags, want)
+			}
+
+			// 指定编码解码
+			if err := decodeZipNames(zr.File, tt.charset); err != nil {
+				t.Fatal(err)
+			}
+			if file.Name != tt.name {
+				t.Fatalf("decoded name = %q, want %q", file.Name, tt.name)
+			}
+		})
+	}
+}
+
+func TestZipNameEncodeError(t *testing.T) {
+	tests := []struct {
+		charset string
+		name    string
+	}{
+		{"unknown", "a.txt"},
+		{"GBK", "emoji😀.txt"},
+		{"Shift_JIS", "한국어.txt"},
+	}
+
+	for _, tt := range tests {
+		z := ZipWriter(&bytes.Buffer{}, ZipOptions{Charset: tt.charset})
+		if ok, err := z.AddReader(tt.name, bytes.NewReader(nil)); ok || err == nil {
+			t.Errorf("%s: %q accepted", tt.charset, tt.name)
+		}
+	}
+}
+
+// TestZipNameDecode 读取时按指定编码或自动检测转换未标记 UTF-8 的名称
+func TestZipNameDecode(t *testing.T) {
+	names := []string{"中文目录/说明文档.txt", "中文目录/会议纪要与报告.txt", "简体中文名称测试.txt"}
+	want := append([]string(nil), names...)
+	sort.Strings(want)
+
+	for _, charset := range []string{"", "GBK"} {
+		t.Run("charset="+charset, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "legacy.zip")
+			os.WriteFile(path, legacyZip(t, "GBK", names...), 0644)
+
+			ar, err := OpenArchive(path, ArchiveReaderOptions{Charset: charset})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ar.Close()
+			if b, err := ar.ReadFile("中文目录/说明文档.txt"); err != nil || string(b) != names[0] {
+				t.Fatalf("ReadFile = %q, %v", b, err)
+			}
+
+			entries, err := FS(path).List(ArchiveReaderOptions{Charset: charset})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, v := range entries {
+				if !v.Mode.IsDir() {
+					got = append(got, v.Name)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("List = %q, want %q", got, want)
+			}
+
+			dir, err := FS(path).Unzip(UnzipOptions{Charset: charset})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b, _ := os.ReadFile(filepath.Join(dir, "中文目录", "会议纪要与报告.txt")); string(b) != names[1] {
+				t.Fatalf("extracted content = %q", b)
+			}
+		})
+	}
+}
+
+// TestZipNameUTF8 标记了 UTF-8 的名称及有效的 UTF-8 名称不被转换
+func TestZipNameUTF8(t *testing.T) {
+	var buf bytes.Buffer
+	z := ZipWriter(&buf)
+	z.Add("中文.txt", []byte("utf8"))
+	z.Add("plain.txt", []byte("ascii"))
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, charset := range []string{"", "GBK"} {
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := decodeZipNames(zr.File, charset); err != nil {
+			t.Fatal(err)
+		}
+		if zr.File[0].Name != "中文.txt" || zr.File[1].Name != "plain.txt" {
+			t.Fatalf("charset %q: names = %q, %q", charset, zr.File[0].Name, zr.File[1].Name)
+		}
+	}
+
+	zr, _ := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err := decodeZipNames(zr.File, "unknown"); err == nil {
+		t.Fatal("unknown charset accepted")
+	}
+}